@@ -0,0 +1,115 @@
+package ast
+
+import "github.com/istsh/markdown-viewer/token"
+
+// NodeType is the kind of a node in the document tree.
+type NodeType string
+
+const (
+	DOCUMENT = "DOCUMENT"
+
+	// container blocks
+	BLOCK_QUOTE = "BLOCK_QUOTE"
+	LIST        = "LIST"
+	LIST_ITEM   = "LIST_ITEM"
+
+	// leaf blocks
+	HEADING    = "HEADING"
+	PARAGRAPH  = "PARAGRAPH"
+	CODE_BLOCK = "CODE_BLOCK"
+	HORIZON    = "HORIZON"
+
+	// a TABLE has TABLE_ROWs of TABLE_CELLs; the first row is the header
+	TABLE      = "TABLE"
+	TABLE_ROW  = "TABLE_ROW"
+	TABLE_CELL = "TABLE_CELL"
+
+	// inlines
+	TEXT       = "TEXT"
+	SOFT_BREAK = "SOFT_BREAK"
+	CODE       = "CODE"
+	EMPHASIS   = "EMPHASIS"
+	STRONG     = "STRONG"
+	LINK       = "LINK"
+	IMAGE      = "IMAGE"
+)
+
+// Alignment is the alignment of a TABLE_CELL.
+type Alignment string
+
+const (
+	ALIGN_NONE   = ""
+	ALIGN_LEFT   = "left"
+	ALIGN_CENTER = "center"
+	ALIGN_RIGHT  = "right"
+)
+
+// Node is a single node of the document tree.
+type Node struct {
+	Type     NodeType
+	Parent   *Node
+	Children []*Node
+
+	// Literal is the content of TEXT, CODE and CODE_BLOCK nodes.
+	Literal []byte
+
+	// Level is the depth of a HEADING.
+	Level int
+
+	// Tight is true for a LIST whose items are not separated by blank lines.
+	Tight bool
+
+	// Ordered is true for a LIST of numbered items.
+	// Start is the number of its first item and Delimiter is `.` or `)`, or `-` for an unordered LIST.
+	// Delimiter is also the character of EMPHASIS and STRONG: `*` or `_`.
+	Ordered   bool
+	Start     int
+	Delimiter byte
+
+	// Info is the info string of a fenced CODE_BLOCK.
+	Info []byte
+
+	// Destination and Title of a LINK or IMAGE.
+	Destination []byte
+	Title       []byte
+
+	// Align is the alignment of a TABLE_CELL, given by the delimiter row of its table.
+	Align Alignment
+
+	// LineEnding is the line ending used by the source of a DOCUMENT: "\n", "\r\n" or "\r".
+	LineEnding []byte
+
+	// Pos is where the node starts in the source and End is where it ends, exclusive.
+	// The line ending after the last line of a block is not a part of it.
+	Pos token.Position
+	End token.Position
+}
+
+// NewNode initializes Node.
+func NewNode(t NodeType) *Node {
+	return &Node{Type: t}
+}
+
+// AppendChild adds child as the last child of n.
+func (n *Node) AppendChild(child *Node) {
+	child.Parent = n
+	n.Children = append(n.Children, child)
+}
+
+// LastChild returns the last child of n, or nil if n has no children.
+func (n *Node) LastChild() *Node {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[len(n.Children)-1]
+}
+
+// IsBlock reports whether n is a block node.
+func (n *Node) IsBlock() bool {
+	switch n.Type {
+	case DOCUMENT, BLOCK_QUOTE, LIST, LIST_ITEM, HEADING, PARAGRAPH, CODE_BLOCK, HORIZON, TABLE, TABLE_ROW, TABLE_CELL:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,50 @@
+package ast
+
+import "encoding/json"
+
+// jsonNode is the JSON form of a Node. The parent is left out, as it is implied by the nesting.
+type jsonNode struct {
+	Type        NodeType `json:"type"`
+	Literal     string   `json:"literal,omitempty"`
+	Level       int      `json:"level,omitempty"`
+	Tight       *bool    `json:"tight,omitempty"`
+	Ordered     bool     `json:"ordered,omitempty"`
+	Start       *int     `json:"start,omitempty"`
+	Delimiter   string   `json:"delimiter,omitempty"`
+	Info        string   `json:"info,omitempty"`
+	Destination *string  `json:"destination,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Align       string   `json:"align,omitempty"`
+	Children    []*Node  `json:"children,omitempty"`
+}
+
+// MarshalJSON writes n and its descendants as a JSON tree.
+// Only the fields that are set for the type of each node are written.
+func (n *Node) MarshalJSON() ([]byte, error) {
+	v := jsonNode{
+		Type:     n.Type,
+		Literal:  string(n.Literal),
+		Level:    n.Level,
+		Info:     string(n.Info),
+		Title:    string(n.Title),
+		Align:    string(n.Align),
+		Children: n.Children,
+	}
+	switch n.Type {
+	case LIST:
+		tight := n.Tight
+		v.Tight = &tight
+		if n.Ordered {
+			v.Ordered = true
+			// a list may start at 0, which is still written
+			start := n.Start
+			v.Start = &start
+			v.Delimiter = string(n.Delimiter)
+		}
+	case LINK, IMAGE:
+		// an empty destination is still written, unlike the other fields
+		destination := string(n.Destination)
+		v.Destination = &destination
+	}
+	return json.Marshal(v)
+}
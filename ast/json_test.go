@@ -0,0 +1,36 @@
+package ast
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	doc := NewNode(DOCUMENT)
+	list := NewNode(LIST)
+	list.Ordered = true
+	list.Start = 0
+	list.Delimiter = ')'
+	doc.AppendChild(list)
+	item := NewNode(LIST_ITEM)
+	list.AppendChild(item)
+	paragraph := NewNode(PARAGRAPH)
+	item.AppendChild(paragraph)
+	link := NewNode(LINK)
+	paragraph.AppendChild(link)
+	text := NewNode(TEXT)
+	text.Literal = []byte("a \"b\"")
+	link.AppendChild(text)
+
+	got, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"type":"DOCUMENT","children":[{"type":"LIST","tight":false,"ordered":true,"start":0,"delimiter":")","children":[` +
+		`{"type":"LIST_ITEM","children":[{"type":"PARAGRAPH","children":[{"type":"LINK","destination":"","children":[` +
+		`{"type":"TEXT","literal":"a \"b\""}]}]}]}]}]}`
+	if string(got) != want {
+		t.Errorf("json wrong.\nexpected=%s\ngot=%s", want, got)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/document"
+)
+
+// markdownExts are the extensions of the files browser shows as html pages.
+var markdownExts = []string{".md", ".markdown"}
+
+// browser serves the files under a directory: markdown files as html pages, directories as lists of their entries,
+// and the other files as they are. The page of a markdown file is also served without its extension,
+// which is how the pages link to each other.
+// Files whose names start with a dot and files outside the directory, even through symbolic links, are not served.
+type browser struct {
+	root string
+	opts document.Options
+}
+
+func (b *browser) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	opts := b.opts
+	if theme := r.URL.Query().Get("theme"); theme != "" {
+		if !document.IsTheme(theme) {
+			http.Error(w, "Invalid theme: "+theme, http.StatusBadRequest)
+			return
+		}
+		opts.Theme = theme
+	}
+	lineNumbers, ok := queryBool(w, r, "line-numbers")
+	if !ok {
+		return
+	}
+	opts.LineNumbers = lineNumbers
+
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+	isDirPath := strings.HasSuffix(r.URL.Path, "/")
+
+	if !isDirPath && markdownExt(name) == "" {
+		for _, ext := range markdownExts {
+			if file, ok := b.resolve(name + ext); ok && isRegular(file) {
+				b.servePage(w, name+ext, file, opts)
+				return
+			}
+		}
+	}
+
+	file, ok := b.resolve(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case info.IsDir() && !isDirPath:
+		// the entries of the list are linked relative to the directory
+		target := r.URL.Path + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	case info.IsDir():
+		b.serveDir(w, r.URL.Path, name, file, opts)
+	case isDirPath:
+		http.NotFound(w, r)
+	case markdownExt(name) != "":
+		b.servePage(w, name, file, opts)
+	default:
+		f, err := os.Open(file)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	}
+}
+
+// resolve returns the path of the file at name, a slash-separated path relative to the root,
+// with its symbolic links resolved. ok is false if the file doesn't exist, is hidden or is outside the root.
+func (b *browser) resolve(name string) (file string, ok bool) {
+	if !fs.ValidPath(name) {
+		return "", false
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem != "." && strings.HasPrefix(elem, ".") {
+			return "", false
+		}
+	}
+
+	root, err := filepath.EvalSymlinks(b.root)
+	if err != nil {
+		return "", false
+	}
+	file, err = filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return file, true
+}
+
+// servePage serves the markdown file at name as an html page, linking its relative links to markdown files
+// to their pages. A page without a title is titled with the name of the file.
+func (b *browser) servePage(w http.ResponseWriter, name string, file string, opts document.Options) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	doc, meta := parseDocument(src)
+	rewriteLinks(doc)
+	if document.Title(doc, meta) == "" {
+		base := path.Base(name)
+		opts.Title = strings.TrimSuffix(base, markdownExt(base))
+	}
+	writePage(w, doc, meta, opts)
+}
+
+// serveDir serves the list of the entries of the directory at name, which is dir, as an html page at urlPath.
+// The entries that aren't served are left out.
+func (b *browser) serveDir(w http.ResponseWriter, urlPath string, name string, dir string, opts document.Options) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	doc := ast.NewNode(ast.DOCUMENT)
+	heading := ast.NewNode(ast.HEADING)
+	heading.Level = 1
+	heading.AppendChild(textNode(urlPath))
+	doc.AppendChild(heading)
+
+	list := ast.NewNode(ast.LIST)
+	list.Tight = true
+	if urlPath != "/" {
+		list.AppendChild(linkItem("../", "../"))
+	}
+	for _, entry := range entries {
+		file, ok := b.resolve(path.Join(name, entry.Name()))
+		if !ok {
+			continue
+		}
+		// the entries behind symbolic links are listed as what they link to
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		switch entry := entry.Name(); {
+		case info.IsDir():
+			list.AppendChild(linkItem(entry+"/", entry+"/"))
+		case markdownExt(entry) != "":
+			list.AppendChild(linkItem(strings.TrimSuffix(entry, markdownExt(entry)), entry))
+		default:
+			list.AppendChild(linkItem(entry, entry))
+		}
+	}
+	doc.AppendChild(list)
+
+	opts.Title = urlPath
+	writePage(w, doc, nil, opts)
+}
+
+// writePage writes doc as an html page once it is complete, so that a failure of the layout can be reported.
+func writePage(w http.ResponseWriter, doc *ast.Node, meta map[string]string, opts document.Options) {
+	var buf bytes.Buffer
+	if err := document.Render(&buf, doc, meta, opts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// rewriteLinks removes the extensions from the links to markdown files on the same server,
+// so that they link to the pages of the files.
+func rewriteLinks(doc *ast.Node) {
+	renderer.Walk(doc, func(node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+		if node.Type != ast.LINK || !entering {
+			return renderer.GoToNext, nil
+		}
+		u, err := url.Parse(string(node.Destination))
+		if err != nil || u.Scheme != "" || u.Host != "" {
+			return renderer.GoToNext, nil
+		}
+		if ext := markdownExt(u.Path); ext != "" && path.Base(u.Path) != ext {
+			u.Path = strings.TrimSuffix(u.Path, ext)
+			node.Destination = []byte(u.String())
+		}
+		return renderer.GoToNext, nil
+	})
+}
+
+// markdownExt returns the extension of name if it is one of a markdown file, or "".
+func markdownExt(name string) string {
+	ext := path.Ext(name)
+	for _, markdown := range markdownExts {
+		if ext == markdown {
+			return ext
+		}
+	}
+	return ""
+}
+
+func isRegular(file string) bool {
+	info, err := os.Stat(file)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func textNode(text string) *ast.Node {
+	node := ast.NewNode(ast.TEXT)
+	node.Literal = []byte(text)
+	return node
+}
+
+// linkItem returns a list item of a link to the relative path href.
+func linkItem(href string, text string) *ast.Node {
+	link := ast.NewNode(ast.LINK)
+	// a path like "a:b" is written as "./a:b", so that it isn't read as a scheme
+	link.Destination = []byte((&url.URL{Path: href}).String())
+	link.AppendChild(textNode(text))
+	paragraph := ast.NewNode(ast.PARAGRAPH)
+	paragraph.AppendChild(link)
+	item := ast.NewNode(ast.LIST_ITEM)
+	item.AppendChild(paragraph)
+	return item
+}
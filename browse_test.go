@@ -0,0 +1,89 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/renderer/document"
+)
+
+func TestBrowse(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	files := map[string]string{
+		"README.md":         "# Home\n\n[a](docs/a.md#x) [b](/docs/b.markdown) [c](https://example.com/c.md) ![d](d.png)\n",
+		"d.png":             "png",
+		".env":              "secret",
+		"docs/a.md":         "[up](../README.md?v=1)\n",
+		"docs/b.markdown":   "---\ntitle: B\n---\nb\n",
+		"docs/.hidden/e.md": "e",
+	}
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "f.md"), []byte("f"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "f.md"), filepath.Join(root, "docs", "f.md")); err != nil {
+		t.Logf("symbolic links aren't tested: %v", err)
+	}
+
+	layout := template.Must(template.New("test").Parse("{{.Title}}|{{.Theme}}|{{.Body}}"))
+	mux := newServeMux(serveConfig{root: root, document: document.Options{Template: layout}})
+	tests := []struct {
+		method string
+		path   string
+		status int
+		want   string
+	}{
+		{"GET", "/README.md", http.StatusOK,
+			"Home|light|<h1>Home</h1>\n<p><a href=\"docs/a#x\">a</a> <a href=\"/docs/b\">b</a> <a href=\"https://example.com/c.md\">c</a> <img src=\"d.png\" alt=\"d\"></p>\n"},
+		{"GET", "/docs/a", http.StatusOK, "a|light|<p><a href=\"../README?v=1\">up</a></p>\n"},
+		{"GET", "/docs/b?theme=dark", http.StatusOK, "B|dark|<p>b</p>\n"},
+		{"GET", "/", http.StatusOK, "/|light|<h1>/</h1>\n<ul>\n<li><a href=\"README\">README.md</a></li>\n" +
+			"<li><a href=\"d.png\">d.png</a></li>\n<li><a href=\"docs/\">docs/</a></li>\n</ul>\n"},
+		{"GET", "/docs/", http.StatusOK, "/docs/|light|<h1>/docs/</h1>\n<ul>\n<li><a href=\"../\">../</a></li>\n" +
+			"<li><a href=\"a\">a.md</a></li>\n<li><a href=\"b\">b.markdown</a></li>\n</ul>\n"},
+		{"GET", "/docs", http.StatusMovedPermanently, "<a href=\"/docs/\">Moved Permanently</a>.\n\n"},
+		{"GET", "/d.png", http.StatusOK, "png"},
+		{"GET", "/.env", http.StatusNotFound, "404 page not found\n"},
+		{"GET", "/docs/.hidden/e", http.StatusNotFound, "404 page not found\n"},
+		{"GET", "/docs/f.md", http.StatusNotFound, "404 page not found\n"},
+		{"GET", "/docs/a.md/", http.StatusNotFound, "404 page not found\n"},
+		{"GET", "/missing", http.StatusNotFound, "404 page not found\n"},
+		{"GET", "/?theme=blue", http.StatusBadRequest, "Invalid theme: blue\n"},
+		{"POST", "/README.md", http.StatusMethodNotAllowed, "Invalid request method\n"},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("tests[%d] - status wrong. expected=%d, got=%d", i, tt.status, rec.Code)
+		}
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("tests[%d] - body wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestBrowseTraversal(t *testing.T) {
+	b := &browser{root: t.TempDir()}
+	for _, name := range []string{"..", "../etc/passwd", "a/../../b", "/etc/passwd", ".git/config"} {
+		if file, ok := b.resolve(name); ok {
+			t.Errorf("%q is resolved to %q", name, file)
+		}
+	}
+}
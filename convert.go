@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/confluence"
+	"github.com/istsh/markdown-viewer/renderer/docx"
+	"github.com/istsh/markdown-viewer/renderer/highlight"
+	"github.com/istsh/markdown-viewer/renderer/html"
+	"github.com/istsh/markdown-viewer/renderer/jira"
+	"github.com/istsh/markdown-viewer/renderer/latex"
+	"github.com/istsh/markdown-viewer/renderer/man"
+	"github.com/istsh/markdown-viewer/renderer/slack"
+	"github.com/istsh/markdown-viewer/renderer/text"
+	"github.com/istsh/markdown-viewer/token"
+)
+
+// stdin is the name of the standard input in the arguments of a command.
+const stdin = "-"
+
+// converter converts the markdown text read from r and writes the result to w.
+type converter func(w io.Writer, r io.Reader) error
+
+// renderOptions are the flags of render that the renderers depend on.
+type renderOptions struct {
+	standalone  bool
+	highlight   bool
+	lineNumbers bool
+}
+
+// formats are the output formats of render by name.
+var formats = map[string]struct {
+	ext         string
+	newRenderer func(opts renderOptions) renderer.Renderer
+	// render writes a whole document, for the formats that can't be converted as they are read.
+	render func(w io.Writer, doc *ast.Node) error
+	// binary is true for the formats whose results can't be written one after another.
+	binary bool
+}{
+	"html": {ext: ".html", newRenderer: func(opts renderOptions) renderer.Renderer {
+		if opts.highlight || opts.lineNumbers {
+			return highlight.NewRenderer(highlight.Options{LineNumbers: opts.lineNumbers})
+		}
+		return html.NewRenderer()
+	}},
+	"text": {ext: ".txt", newRenderer: func(renderOptions) renderer.Renderer { return text.NewRenderer() }},
+	"latex": {ext: ".tex", newRenderer: func(opts renderOptions) renderer.Renderer {
+		return latex.NewRenderer(latex.Options{Standalone: opts.standalone})
+	}},
+	"man":        {ext: ".1", newRenderer: func(renderOptions) renderer.Renderer { return man.NewRenderer() }},
+	"docx":       {ext: ".docx", render: docx.Render, binary: true},
+	"slack":      {ext: ".mrkdwn", newRenderer: func(renderOptions) renderer.Renderer { return slack.NewRenderer() }},
+	"jira":       {ext: ".jira", newRenderer: func(renderOptions) renderer.Renderer { return jira.NewRenderer() }},
+	"confluence": {ext: ".xhtml", newRenderer: func(renderOptions) renderer.Renderer { return confluence.NewRenderer() }},
+}
+
+// render converts markdown to html, or to the format named by the -format flag.
+func render(args []string) error {
+	fs, output := newConvertFlagSet("render")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: markdown-viewer render [-format FORMAT] [-standalone [-theme THEME] [-template FILE]] [-highlight] [-line-numbers] [-o PATH] FILE|-...")
+		fs.PrintDefaults()
+	}
+	name := fs.String("format", "html", "convert to `FORMAT`: html, text, latex, man, docx, slack, jira or confluence")
+	var opts renderOptions
+	fs.BoolVar(&opts.standalone, "standalone", false, "write a complete document instead of a body to include (html, latex)")
+	fs.BoolVar(&opts.highlight, "highlight", false, "highlight the code blocks (html; always with -standalone)")
+	fs.BoolVar(&opts.lineNumbers, "line-numbers", false, "number the lines of the code blocks (html)")
+	documentOptions := addDocumentFlags(fs)
+	fs.Parse(args)
+
+	format, ok := formats[*name]
+	if !ok {
+		return fmt.Errorf("unknown format %q", *name)
+	}
+	pageOptions, err := documentOptions()
+	if err != nil {
+		return err
+	}
+	pageOptions.LineNumbers = opts.lineNumbers
+	return convertInputs(fs, *output, format.ext, format.binary, func(w io.Writer, r io.Reader) error {
+		if *name == "html" && opts.standalone {
+			// the title of the page is known once the whole document is read
+			return renderDocument(w, r, pageOptions)
+		}
+
+		l := lexer.NewReader(r)
+		if format.render != nil {
+			doc := parser.New(l).ParseDocument()
+			if err := l.Err(); err != nil {
+				return err
+			}
+			return format.render(w, doc)
+		}
+
+		nr := format.newRenderer(opts)
+		if !opts.standalone {
+			// the body is converted as it is read
+			if err := parser.New(l).RenderTo(w, nr); err != nil {
+				return err
+			}
+		} else {
+			doc := parser.New(l).ParseDocument()
+			if err := l.Err(); err != nil {
+				return err
+			}
+			if err := renderer.Render(w, nr, doc); err != nil {
+				return err
+			}
+		}
+		// the constructs the format doesn't have are reported, but the conversion goes on
+		if warner, ok := nr.(renderer.Warner); ok {
+			for _, warning := range warner.Warnings() {
+				fmt.Fprintln(os.Stderr, "warning:", warning)
+			}
+		}
+		return nil
+	})
+}
+
+// tokens writes the tokens of the lexer, one per line.
+func tokens(args []string) error {
+	return convert("tokens", ".tokens", args, func(w io.Writer, r io.Reader) error {
+		l := lexer.NewReader(r)
+		bw := bufio.NewWriter(w)
+		for {
+			tok := l.NextToken()
+			if tok.Type == token.EOF {
+				break
+			}
+			fmt.Fprintln(bw, tok)
+		}
+		if err := l.Err(); err != nil {
+			return err
+		}
+		return bw.Flush()
+	})
+}
+
+// printAST writes the document tree as JSON.
+func printAST(args []string) error {
+	return convert("ast", ".json", args, func(w io.Writer, r io.Reader) error {
+		l := lexer.NewReader(r)
+		doc := parser.New(l).ParseDocument()
+		if err := l.Err(); err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	})
+}
+
+// convert runs fn on each file named by args, which may be glob patterns or "-" for the standard input.
+// The results are written to the standard output, or to the file named by the -o flag.
+// With several inputs, -o names a directory and each result is named after its input with ext;
+// inputs with the same name in different directories are an error.
+func convert(name string, ext string, args []string, fn converter) error {
+	fs, output := newConvertFlagSet(name)
+	fs.Parse(args)
+	return convertInputs(fs, *output, ext, false, fn)
+}
+
+// newConvertFlagSet returns the flags of a command run by convertInputs, with the -o flag.
+// The command may add flags of its own before parsing its arguments.
+func newConvertFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: markdown-viewer %s [-o PATH] FILE|-...\n", name)
+		fs.PrintDefaults()
+	}
+	output := fs.String("o", "", "write to `PATH` instead of the standard output; a directory if there are several inputs")
+	return fs, output
+}
+
+// convertInputs runs fn on each file named by the arguments of the parsed fs, and writes the results to output. See convert.
+// The results in a binary format, which can't be told apart once they are written one after another,
+// are only written to the standard output for a single input.
+func convertInputs(fs *flag.FlagSet, output string, ext string, binary bool, fn converter) error {
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	inputs, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		if binary && len(inputs) > 1 {
+			return errors.New("the results of several inputs can't be written to the standard output in this format; use -o DIR")
+		}
+		for _, input := range inputs {
+			if err := convertFile(os.Stdout, input, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(inputs) == 1 && !isDir(output) {
+		return convertTo(output, inputs[0], fn)
+	}
+	// the names are checked before anything is written, so that no result replaces another
+	paths := make([]string, len(inputs))
+	sources := map[string]string{}
+	for i, input := range inputs {
+		if input == stdin {
+			return errors.New("the standard input can't be written to a directory")
+		}
+		base := filepath.Base(input)
+		paths[i] = filepath.Join(output, strings.TrimSuffix(base, filepath.Ext(base))+ext)
+		if source, ok := sources[paths[i]]; ok {
+			return fmt.Errorf("%s and %s would both be written to %s", source, input, paths[i])
+		}
+		sources[paths[i]] = input
+	}
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return err
+	}
+	for i, input := range inputs {
+		if err := convertTo(paths[i], input, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandInputs replaces the glob patterns in args with the names of the files that match them.
+func expandInputs(args []string) ([]string, error) {
+	var inputs []string
+	for _, arg := range args {
+		if arg == stdin || !strings.ContainsAny(arg, "*?[") {
+			inputs = append(inputs, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", arg)
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs, nil
+}
+
+// convertTo writes the result of fn for input to the file at path.
+func convertTo(path string, input string, fn converter) error {
+	return createFile(path, func(w io.Writer) error {
+		return convertFile(w, input, fn)
+	})
+}
+
+// createFile writes what write writes to the file at path.
+// If write fails, the partly written file isn't left behind.
+func createFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+func convertFile(w io.Writer, input string, fn converter) error {
+	name, r := "standard input", io.Reader(os.Stdin)
+	if input != stdin {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		name, r = input, f
+	}
+
+	if err := fn(w, r); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "b.md", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := expandInputs([]string{filepath.Join(dir, "*.md"), "-", "missing.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md"), "-", "missing.md"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inputs wrong.\nexpected=%q\ngot=%q", want, got)
+	}
+
+	if _, err := expandInputs([]string{filepath.Join(dir, "*.html")}); err == nil {
+		t.Error("error is not returned for a pattern without matches")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "b.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("*"+name+"*\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	upper := func(w io.Writer, r io.Reader) error {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, strings.ToUpper(string(b)))
+		return err
+	}
+
+	// a single input is written to the file
+	file := filepath.Join(dir, "a.out")
+	if err := convert("test", ".out", []string{"-o", file, filepath.Join(dir, "a.md")}, upper); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, file, "*A.MD*\n")
+
+	// several inputs are written to the directory
+	out := filepath.Join(dir, "out")
+	if err := convert("test", ".out", []string{"-o", out, filepath.Join(dir, "*.md")}, upper); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, filepath.Join(out, "a.out"), "*A.MD*\n")
+	assertFile(t, filepath.Join(out, "b.out"), "*B.MD*\n")
+
+	// inputs of the same name would replace each other's results
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "a.md"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := convert("test", ".out", []string{"-o", out, filepath.Join(dir, "a.md"), filepath.Join(sub, "a.md")}, upper); err == nil {
+		t.Error("error is not returned for inputs of the same name")
+	}
+	assertFile(t, filepath.Join(out, "a.out"), "*A.MD*\n")
+
+	// a failed conversion leaves no partly written file
+	failed := filepath.Join(dir, "failed.out")
+	fail := func(w io.Writer, r io.Reader) error {
+		io.WriteString(w, "partial")
+		return errors.New("read error")
+	}
+	if err := convert("test", ".out", []string{"-o", failed, filepath.Join(dir, "a.md")}, fail); err == nil {
+		t.Error("error is not returned for a failed conversion")
+	}
+	if _, err := os.Stat(failed); !os.IsNotExist(err) {
+		t.Errorf("partly written file is left: %v", err)
+	}
+}
+
+func TestConvertFileError(t *testing.T) {
+	fail := func(w io.Writer, r io.Reader) error {
+		return errors.New("read error")
+	}
+
+	saved := os.Stdin
+	defer func() { os.Stdin = saved }()
+	os.Stdin = nil
+	err := convertFile(io.Discard, stdin, fail)
+	if want := "standard input: read error"; err == nil || err.Error() != want {
+		t.Errorf("error wrong. expected=%q, got=%v", want, err)
+	}
+
+	input := filepath.Join(t.TempDir(), "a.md")
+	if err := os.WriteFile(input, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = convertFile(io.Discard, input, fail)
+	if want := input + ": read error"; err == nil || err.Error() != want {
+		t.Errorf("error wrong. expected=%q, got=%v", want, err)
+	}
+}
+
+func TestRenderFormat(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "a.md")
+	if err := os.WriteFile(input, []byte("# a_b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "a.tex")
+	if err := render([]string{"-format", "latex", "-o", out, input}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, out, "\\section{a\\_b}\n")
+
+	out = filepath.Join(dir, "a.1")
+	if err := render([]string{"-format", "man", "-o", out, input}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, out, ".TH \"A_B\" \"1\"\n")
+
+	out = filepath.Join(dir, "a.html")
+	layout := filepath.Join(dir, "layout.html")
+	if err := os.WriteFile(layout, []byte("{{.Title}}|{{.Theme}}|{{.Body}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := render([]string{"-standalone", "-theme", "dark", "-template", layout, "-o", out, input}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, out, "a_b|dark|<h1>a_b</h1>\n")
+
+	code := filepath.Join(dir, "code.md")
+	if err := os.WriteFile(code, []byte("```go\nvar a\n```\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out = filepath.Join(dir, "code.html")
+	if err := render([]string{"-highlight", "-o", out, code}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, out, "<pre><code class=\"language-go\"><span class=\"tok-keyword\">var</span> a\n</code></pre>\n")
+
+	// docx is converted as a whole document
+	out = filepath.Join(dir, "a.docx")
+	if err := render([]string{"-format", "docx", "-o", out, input}); err != nil {
+		t.Fatal(err)
+	}
+	if z, err := zip.OpenReader(out); err != nil {
+		t.Errorf("%s is not a zip archive: %v", out, err)
+	} else {
+		z.Close()
+	}
+
+	// docx archives can't be written to the standard output one after another
+	if err := render([]string{"-format", "docx", input, input}); err == nil {
+		t.Error("error is not returned for several docx inputs to the standard output")
+	}
+
+	if err := render([]string{"-format", "pdf", input}); err == nil {
+		t.Error("error is not returned for an unknown format")
+	}
+	if err := render([]string{"-standalone", "-theme", "blue", input}); err == nil {
+		t.Error("error is not returned for an unknown theme")
+	}
+}
+
+func assertFile(t *testing.T, path string, want string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("%s wrong.\nexpected=%q\ngot=%q", path, want, got)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+	"github.com/istsh/markdown-viewer/renderer/document"
+)
+
+// addDocumentFlags adds the flags of html documents to fs.
+// The returned function gives the options they set once fs is parsed.
+func addDocumentFlags(fs *flag.FlagSet) func() (document.Options, error) {
+	theme := fs.String("theme", document.DefaultTheme, "style html pages with the built-in `THEME`: light or dark")
+	layout := fs.String("template", "", "lay out html pages with the html/template in `FILE` instead of the built-in one")
+	return func() (document.Options, error) {
+		if !document.IsTheme(*theme) {
+			return document.Options{}, fmt.Errorf("unknown theme %q", *theme)
+		}
+		opts := document.Options{Theme: *theme}
+		if *layout != "" {
+			t, err := template.ParseFiles(*layout)
+			if err != nil {
+				return document.Options{}, err
+			}
+			opts.Template = t
+		}
+		return opts, nil
+	}
+}
+
+// renderDocument converts the markdown text read from r to a complete html page.
+// The front matter of the text is not shown, but its fields are given to the layout.
+func renderDocument(w io.Writer, r io.Reader, opts document.Options) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	doc, meta := parseDocument(src)
+	return document.Render(w, doc, meta, opts)
+}
+
+// parseDocument parses markdown text without its front matter, and returns the fields of the front matter as well.
+func parseDocument(src []byte) (*ast.Node, map[string]string) {
+	meta, body := document.SplitFrontMatter(src)
+	return parser.New(lexer.New(body)).ParseDocument(), meta
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+	"github.com/istsh/markdown-viewer/renderer/epub"
+)
+
+// makeBook writes markdown files as the chapters of an EPUB book, in the order they are given.
+// A directory stands for the markdown files in it in the order of their names.
+func makeBook(args []string) error {
+	fs := flag.NewFlagSet("epub", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: markdown-viewer epub -o BOOK [-title TITLE] [-lang LANG] FILE|DIR...")
+		fs.PrintDefaults()
+	}
+	output := fs.String("o", "", "write the book to `BOOK`")
+	title := fs.String("title", "", "the `TITLE` of the book; the first heading by default")
+	language := fs.String("lang", "en", "the `LANG`uage of the book")
+	fs.Parse(args)
+	if fs.NArg() == 0 || *output == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	inputs, err := bookInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var chapters []epub.Chapter
+	for _, input := range inputs {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		l := lexer.NewReader(f)
+		doc := parser.New(l).ParseDocument()
+		f.Close()
+		if err := l.Err(); err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		chapters = append(chapters, epub.Chapter{Name: filepath.ToSlash(input), Doc: doc})
+	}
+
+	return createFile(*output, func(w io.Writer) error {
+		return epub.Render(w, chapters, epub.Options{
+			Title:    *title,
+			Language: *language,
+			ReadFile: func(name string) ([]byte, error) {
+				return os.ReadFile(filepath.FromSlash(name))
+			},
+		})
+	})
+}
+
+// bookInputs expands the glob patterns in args like expandInputs, and the directories to the markdown files in them.
+func bookInputs(args []string) ([]string, error) {
+	expanded, err := expandInputs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []string
+	for _, input := range expanded {
+		if input == stdin {
+			return nil, fmt.Errorf("the standard input can't be a chapter")
+		}
+		if !isDir(input) {
+			inputs = append(inputs, input)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(input, "*.md"))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no markdown files in %q", input)
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMakeBook(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"01-a.md": "# A\n![i](i.png)\n", "02-b.md": "# B\n", "i.png": "png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := filepath.Join(dir, "book.epub")
+	if err := makeBook([]string{"-o", out, dir}); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+
+	names := map[string]bool{}
+	for _, f := range z.File {
+		names[f.Name] = true
+	}
+	for _, name := range []string{"EPUB/chapter-1.xhtml", "EPUB/chapter-2.xhtml", "EPUB/images/image-1.png"} {
+		if !names[name] {
+			t.Errorf("%s is missing", name)
+		}
+	}
+}
+
+func TestMakeBookError(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "a.md")
+	if err := os.WriteFile(input, []byte("![i](missing.png)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "book.epub")
+	if err := makeBook([]string{"-o", out, input}); err == nil {
+		t.Fatal("error is not returned for a missing image")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("%s is left behind: %v", out, err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+	"github.com/istsh/markdown-viewer/renderer/markdown"
+)
+
+// defaultFormatWidth is the number of columns paragraphs are reflowed to by fmt and /format.
+const defaultFormatWidth = 80
+
+// formatFiles rewrites markdown files in the canonical form of the markdown renderer.
+// The results are written to the standard output, or back to the files with -write.
+// With -check, the files that aren't formatted are listed instead and an error is returned.
+func formatFiles(args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: markdown-viewer fmt [-check] [-write] [-width N] FILE|-...")
+		fs.PrintDefaults()
+	}
+	check := fs.Bool("check", false, "list the files that aren't formatted and fail if there are any")
+	write := fs.Bool("write", false, "write the results back to the files")
+	width := fs.Int("width", defaultFormatWidth, "reflow paragraphs to `N` columns; 0 keeps their line breaks")
+	fs.Parse(args)
+	if fs.NArg() == 0 || *width < 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	inputs, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	unformatted := 0
+	for _, input := range inputs {
+		src, err := readInput(input)
+		if err != nil {
+			return err
+		}
+		result := format(src, *width)
+
+		switch {
+		case *check:
+			if !bytes.Equal(src, result) {
+				fmt.Println(input)
+				unformatted++
+			}
+		case *write && input != stdin:
+			if bytes.Equal(src, result) {
+				continue
+			}
+			info, err := os.Stat(input)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(input, result, info.Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			if _, err := os.Stdout.Write(result); err != nil {
+				return err
+			}
+		}
+	}
+
+	if unformatted > 0 {
+		return fmt.Errorf("%d of %d files are not formatted", unformatted, len(inputs))
+	}
+	return nil
+}
+
+// format returns the markdown text src in the canonical form, keeping its line endings.
+func format(src []byte, width int) []byte {
+	doc := parser.New(lexer.New(src)).ParseDocument()
+	return markdown.Render(doc, markdown.Options{PreserveLineEnding: true, Width: width})
+}
+
+func readInput(input string) ([]byte, error) {
+	if input == stdin {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(input)
+}
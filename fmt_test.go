@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatFiles(t *testing.T) {
+	dir := t.TempDir()
+	formatted := filepath.Join(dir, "a.md")
+	unformatted := filepath.Join(dir, "b.md")
+	if err := os.WriteFile(formatted, []byte("# a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(unformatted, []byte("#   b\n***\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// -check fails for the file that isn't formatted, and leaves it
+	if err := formatFiles([]string{"-check", filepath.Join(dir, "*.md")}); err == nil {
+		t.Error("error is not returned for a file that isn't formatted")
+	}
+	assertFile(t, unformatted, "#   b\n***\n")
+
+	if err := formatFiles([]string{"-write", filepath.Join(dir, "*.md")}); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, formatted, "# a\n")
+	assertFile(t, unformatted, "# b\n\n---\n")
+
+	if err := formatFiles([]string{"-check", filepath.Join(dir, "*.md")}); err != nil {
+		t.Errorf("error is returned for formatted files: %v", err)
+	}
+}
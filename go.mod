@@ -1,5 +1,5 @@
 module github.com/istsh/markdown-viewer
 
-go 1.12
+go 1.18
 
 require github.com/istsh/go-writing-an-interpreter v0.0.0-20191020160541-e08eec8ee071 // indirect
@@ -1,8 +1,12 @@
 package lexer
 
 import (
+	"bufio"
 	"bytes"
-	"regexp"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/istsh/markdown-viewer/token"
 )
@@ -16,21 +20,33 @@ const (
 	LINE_BREAK_CODE_R = '\r'
 	GT                = '>'
 	BACK_QUOTE        = '`'
+	TILDE             = '~'
 	ASTERISK          = '*'
 	UNDER_SCORE       = '_'
+	EXCLAMATION       = '!'
 	LBRACKET          = '['
 	RBRACKET          = ']'
 	LPAREN            = '('
 	RPAREN            = ')'
+	DOT               = '.'
 	EOF               = 0
 )
 
-var (
-	regexpUrl = regexp.MustCompile(`\[.*]\(http(s)?://([\w-]+\.)+[\w-]+(/[\w- ./?%&=]*)?\)`)
-)
-
+// Lexer reads markdown text line by line and splits it into tokens.
+// Lookahead never goes beyond the end of the current line,
+// so only one line of the input is held in memory at a time, however long that line is.
 type Lexer struct {
+	reader *bufio.Reader
+	err    error
+	eof    bool
+
+	// 読み込み中の1行(末尾は必ず改行コード)
 	input []byte
+	// 読み込んだが、まだ次の行に渡していない部分(CRだけで改行されている場合)
+	pending []byte
+
+	// 最初に現れた改行コード
+	lineEnding []byte
 
 	currentPosition int
 	nextPosition    int
@@ -38,31 +54,105 @@ type Lexer struct {
 	currentCh byte
 	beforeCh  byte
 
-	startedBackQuoteArea   bool
-	startedAsteriskToken   token.TokenType
-	startedUnderScoreToken token.TokenType
-	startedLinkText        token.TokenType
+	// 行頭、または引用記号の直後など、ブロックが始まりうる位置にいるか
+	blockStart bool
+
+	// 次のトークンの開始位置
+	pos token.Position
+	// 入力の最後に改行コードを補ったか
+	addedLineFeed bool
 }
 
+// New initializes Lexer with the whole markdown text.
 func New(input []byte) *Lexer {
-	// 必ず最後は改行コードで終わらせたい
-	if !bytes.HasSuffix(input, []byte{LINE_BREAK_CODE_N}) {
-		input = append(input, LINE_BREAK_CODE_N)
-	}
+	return NewReader(bytes.NewReader(input))
+}
 
+// NewReader initializes Lexer that reads markdown text from r as it tokenizes.
+func NewReader(r io.Reader) *Lexer {
 	l := &Lexer{
-		input:                  input,
-		beforeCh:               LINE_BREAK_CODE_N, // 直前の文字の初期値は改行コード
-		startedAsteriskToken:   token.NONE,
-		startedUnderScoreToken: token.NONE,
+		reader:     bufio.NewReader(r),
+		currentCh:  LINE_BREAK_CODE_N, // 読み込み前は、直前の行が改行コードで終わったものとみなす
+		beforeCh:   LINE_BREAK_CODE_N, // 直前の文字の初期値は改行コード
+		blockStart: true,
+		pos:        token.Position{Line: 1, Column: 1},
 	}
+	l.readLine()
 
 	return l
 }
 
+// Err returns the first error other than io.EOF that occurred while reading the input.
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// LineEnding returns the first line ending of the input read so far: "\n", "\r\n" or "\r".
+// It is "\n" if no line has ended yet.
+func (l *Lexer) LineEnding() []byte {
+	if l.lineEnding == nil {
+		return []byte{LINE_BREAK_CODE_N}
+	}
+	return l.lineEnding
+}
+
+// readLine reads the next line, which ends with "\n", "\r\n" or "\r".
+func (l *Lexer) readLine() {
+	line := l.pending
+	l.pending = nil
+	if len(line) == 0 {
+		var err error
+		line, err = l.reader.ReadBytes(LINE_BREAK_CODE_N)
+		if err != nil && err != io.EOF {
+			l.err = err
+		}
+	}
+	if len(line) == 0 {
+		l.input = nil
+		l.eof = true
+		return
+	}
+
+	// CRだけの改行コードで行を分け、残りは次の行にする
+	if i := bytes.IndexByte(line, LINE_BREAK_CODE_R); i >= 0 && i+1 < len(line) && line[i+1] != LINE_BREAK_CODE_N {
+		l.pending = line[i+1:]
+		line = line[: i+1 : i+1]
+	}
+
+	// 必ず最後は改行コードで終わらせたい
+	if !isLineBreakCode(line[len(line)-1]) {
+		line = append(line, LINE_BREAK_CODE_N)
+		l.addedLineFeed = true
+	} else if l.lineEnding == nil {
+		if bytes.HasSuffix(line, []byte{LINE_BREAK_CODE_R, LINE_BREAK_CODE_N}) {
+			l.lineEnding = []byte{LINE_BREAK_CODE_R, LINE_BREAK_CODE_N}
+		} else {
+			l.lineEnding = []byte{line[len(line)-1]}
+		}
+	}
+	l.input = line
+}
+
+// NextToken returns the next token of the input, or an EOF token at the end.
+// The position of the token is set from the literals of the tokens before it.
+// A line feed added to the end of the input takes no space, so positions never go past the end of the input.
 func (l *Lexer) NextToken() token.Token {
+	tok := l.nextToken()
+	tok.Pos = l.pos
+	if tok.Type != token.LINE_FEED_CODE || !l.addedLineFeed {
+		l.pos = tok.End()
+	}
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
 	// 1文字進める
 	l.readChar()
+	position := l.currentPosition
+
+	if l.eof {
+		return newToken(token.EOF)
+	}
 
 	// 空白もタブも改行も、全てスキップせずに解析していく
 
@@ -70,48 +160,32 @@ func (l *Lexer) NextToken() token.Token {
 
 	switch l.currentCh {
 	case SHARP:
-		if isLineBreakCode(l.beforeCh) {
+		if l.blockStart {
 			literal := l.readHeading()
 			nextCh := l.peekNextChar()
-			if isSpace(nextCh) {
+			if isSpace(nextCh) || isTab(nextCh) {
 				tok = newToken(token.GetHeadingToken(len(literal)))
 				// 空白をスキップする
 				l.readChar()
 			} else if isLineBreakCode(nextCh) && len(literal) == 3 {
-				l.readChar()
 				tok = newToken(token.HORIZON)
 			} else {
-				l.readChar()
-				var tmpChs []byte
-				tmpChs = append(tmpChs, literal...)
-				tmpChs = append(tmpChs, l.readString()...)
-				tok = newToken(token.STRING, tmpChs...)
+				tok = newToken(token.STRING, l.readStringAfter(literal)...)
 			}
 		} else {
 			tok = newToken(token.STRING, l.readString()...)
 		}
 	case HYPHEN:
-		if isLineBreakCode(l.beforeCh) {
+		if l.blockStart {
 			literal := l.readHyphen()
 			nextCh := l.peekNextChar()
-			if isLineBreakCode(nextCh) && len(literal) == 3 {
+			if len(literal) == 3 && l.skipTrailingBlanks() {
 				tok = newToken(token.HORIZON)
-			} else if isSpace(nextCh) && len(literal) == 1 {
+			} else if (isSpace(nextCh) || isTab(nextCh) || isLineBreakCode(nextCh)) && len(literal) == 1 {
+				// リストの記号(ネストの深さは、インデントの幅からパーサーが決める)
 				tok = newToken(token.HYPHEN, literal...)
 			} else {
-				l.readChar()
-				var tmpChs []byte
-				tmpChs = append(tmpChs, literal...)
-				tmpChs = append(tmpChs, l.readString()...)
-				tok = newToken(token.STRING, tmpChs...)
-			}
-		} else if isTab(l.beforeCh) {
-			if isLineBreakCode(l.twoBeforeChar()) {
-				tok = newToken(token.HYPHEN, l.currentCh)
-			} else if isTab(l.twoBeforeChar()) && isLineBreakCode(l.threeBeforeChar()) {
-				tok = newToken(token.HYPHEN, l.currentCh)
-			} else {
-				tok = newToken(token.STRING, l.currentCh)
+				tok = newToken(token.STRING, l.readStringAfter(literal)...)
 			}
 		} else {
 			tok = newToken(token.STRING, l.readString()...)
@@ -123,285 +197,92 @@ func (l *Lexer) NextToken() token.Token {
 	case SPACE:
 		tok = newToken(token.SPACE)
 	case LINE_BREAK_CODE_N, LINE_BREAK_CODE_R:
-		l.startedBackQuoteArea = false
-		l.startedAsteriskToken = token.NONE
-		l.startedUnderScoreToken = token.NONE
+		// CRLFは1つの改行として扱う
+		if l.currentCh == LINE_BREAK_CODE_R && l.peekNextChar() == LINE_BREAK_CODE_N {
+			l.readChar()
+		}
 		tok = newToken(token.LINE_FEED_CODE)
 	case GT:
-		if isLineBreakCode(l.beforeCh) {
-			literal := l.readCitation()
-			tok = newToken(token.GetCitationToken(len(literal)))
+		if l.blockStart {
+			// 引用記号は1つずつトークンにする(ネストの深さはパーサーが数える)
+			tok = newToken(token.CITATION, l.currentCh)
 		} else {
 			tok = newToken(token.STRING, l.readString()...)
 		}
 	case BACK_QUOTE:
-		if l.startedBackQuoteArea {
-			nextCh := l.peekNextChar()
-			if isSpace(nextCh) || isLineBreakCode(nextCh) {
-				tok = newToken(token.BACK_QUOTE_FINISH)
-			} else {
-				tok = newToken(token.STRING, l.readString()...)
-			}
-			l.startedBackQuoteArea = false
+		if l.blockStart && l.countRun(BACK_QUOTE) >= 3 {
+			tok = newToken(token.CODE_FENCE, l.readRun(BACK_QUOTE)...)
 		} else {
-			switch {
-			case isLineBreakCode(l.beforeCh):
-				if l.existsByEndOfLine([]byte("` ")) {
-					l.startedBackQuoteArea = true
-					tok = newToken(token.BACK_QUOTE_BEGIN)
-				} else {
-					l.startedBackQuoteArea = false
-					tok = newToken(token.STRING, l.readString()...)
-				}
-			case isSpace(l.beforeCh):
-				if l.existsByEndOfLine([]byte("` ")) {
-					l.startedBackQuoteArea = true
-					tok = newToken(token.BACK_QUOTE_BEGIN)
-				} else {
-					l.startedBackQuoteArea = false
-					tok = newToken(token.STRING, l.readString()...)
-				}
-			default:
-				l.startedBackQuoteArea = false
-				tok = newToken(token.STRING, l.readString()...)
-			}
+			// 対応する閉じ記号(同じ長さの連続)は、パーサーが探す
+			tok = newToken(token.BACK_QUOTE, l.readRun(BACK_QUOTE)...)
 		}
 	case ASTERISK:
-		if l.startedAsteriskToken != token.NONE {
-			switch l.startedAsteriskToken {
-			case token.ASTERISK_ITALIC_BEGIN:
-				nextCh := l.peekNextChar()
-				if isSpace(nextCh) || isLineBreakCode(nextCh) {
-					tok = newToken(token.ASTERISK_ITALIC_FINISH)
-				} else {
-					tok = newToken(token.STRING, l.readString()...)
-				}
-				l.startedAsteriskToken = token.NONE
-			case token.ASTERISK_BOLD_BEGIN:
-				if isAsterisk(l.peekNextChar()) {
-					peek2ndOrderChar := l.peek2ndOrderChar()
-					if isSpace(peek2ndOrderChar) || isLineBreakCode(peek2ndOrderChar) {
-						l.readAsterisk()
-						tok = newToken(token.ASTERISK_BOLD_FINISH)
-					} else {
-						tok = newToken(token.STRING, l.readString()...)
-					}
-				} else {
-					tok = newToken(token.STRING, l.readString()...)
-				}
-				l.startedAsteriskToken = token.NONE
-			case token.ASTERISK_ITALIC_BOLD_BEGIN:
-				if isAsterisk(l.peekNextChar()) {
-					if isAsterisk(l.peek2ndOrderChar()) {
-						peek3ndOrderChar := l.peek3ndOrderChar()
-						if isSpace(peek3ndOrderChar) || isLineBreakCode(peek3ndOrderChar) {
-							l.readAsterisk()
-							tok = newToken(token.ASTERISK_ITALIC_BOLD_FINISH)
-						} else {
-							tok = newToken(token.STRING, l.readString()...)
-						}
-					} else {
-						tok = newToken(token.STRING, l.readString()...)
-					}
-				} else {
-					tok = newToken(token.STRING, l.readString()...)
-				}
-				l.startedAsteriskToken = token.NONE
-			}
-
+		literal := l.readAsterisk()
+		if l.blockStart && len(literal) == 3 && l.skipTrailingBlanks() {
+			tok = newToken(token.HORIZON)
 		} else {
-			switch {
-			case isLineBreakCode(l.beforeCh):
-				literal := l.readAsterisk()
-				var tmpChs []byte
-				tmpChs = append(tmpChs, literal...)
-				tmpChs = append(tmpChs, SPACE)
-				if l.existsByEndOfLine(tmpChs) {
-					tokenType := token.GetAsteriskToken(len(literal))
-					l.startedAsteriskToken = tokenType
-					tok = newToken(tokenType)
-				} else if isLineBreakCode(l.peekNextChar()) && len(literal) == 3 {
-					tok = newToken(token.HORIZON)
-				} else {
-					tmpChs = nil
-					tmpChs = append(tmpChs, literal...)
-					tmpChs = append(tmpChs, LINE_BREAK_CODE_N)
-					if l.existsByEndOfLine(tmpChs) {
-						tokenType := token.GetAsteriskToken(len(literal))
-						l.startedAsteriskToken = tokenType
-						tok = newToken(tokenType)
-					} else {
-						l.startedAsteriskToken = token.NONE
-						var tmpChs []byte
-						tmpChs = append(tmpChs, literal...)
-						tmpChs = append(tmpChs, l.readString()...)
-						tok = newToken(token.STRING, tmpChs...)
-					}
-				}
-			case isSpace(l.beforeCh):
-				literal := l.readAsterisk()
-				var tmpChs []byte
-				tmpChs = append(tmpChs, literal...)
-				tmpChs = append(tmpChs, ' ')
-				if l.existsByEndOfLine(tmpChs) {
-					tokenType := token.GetAsteriskToken(len(literal))
-					l.startedAsteriskToken = tokenType
-					tok = newToken(tokenType)
-				} else {
-					tmpChs = nil
-					tmpChs = append(tmpChs, literal...)
-					tmpChs = append(tmpChs, LINE_BREAK_CODE_N)
-					if l.existsByEndOfLine(tmpChs) {
-						tokenType := token.GetAsteriskToken(len(literal))
-						l.startedAsteriskToken = tokenType
-						tok = newToken(tokenType)
-					} else {
-						l.startedAsteriskToken = token.NONE
-						var tmpChs []byte
-						tmpChs = append(tmpChs, literal...)
-						tmpChs = append(tmpChs, l.readString()...)
-						tok = newToken(token.STRING, tmpChs...)
-					}
-				}
-			default:
-				l.startedAsteriskToken = token.NONE
-				tok = newToken(token.STRING, l.readString()...)
-			}
+			// 強調になるかどうかは、前後の文脈を見てパーサーが決める
+			tok = newToken(token.ASTERISK, literal...)
 		}
 	case UNDER_SCORE:
-		if l.startedUnderScoreToken != token.NONE {
-			switch l.startedUnderScoreToken {
-			case token.UNDER_SCORE_ITALIC_BEGIN:
-				nextCh := l.peekNextChar()
-				if isSpace(nextCh) || isLineBreakCode(nextCh) {
-					tok = newToken(token.UNDER_SCORE_ITALIC_FINISH)
-				} else {
-					tok = newToken(token.STRING, l.readString()...)
-				}
-				l.startedUnderScoreToken = token.NONE
-			case token.UNDER_SCORE_BOLD_BEGIN:
-				if isUnderScore(l.peekNextChar()) {
-					peek2ndOrderChar := l.peek2ndOrderChar()
-					if isSpace(peek2ndOrderChar) || isLineBreakCode(peek2ndOrderChar) {
-						l.readUnderScore()
-						tok = newToken(token.UNDER_SCORE_BOLD_FINISH)
-					} else {
-						tok = newToken(token.STRING, l.readString()...)
-					}
-				} else {
-					tok = newToken(token.STRING, l.readString()...)
-				}
-				l.startedUnderScoreToken = token.NONE
-			case token.UNDER_SCORE_ITALIC_BOLD_BEGIN:
-				if isUnderScore(l.peekNextChar()) {
-					if isUnderScore(l.peek2ndOrderChar()) {
-						peek3ndOrderChar := l.peek3ndOrderChar()
-						if isSpace(peek3ndOrderChar) || isLineBreakCode(peek3ndOrderChar) {
-							l.readUnderScore()
-							tok = newToken(token.UNDER_SCORE_ITALIC_BOLD_FINISH)
-						} else {
-							tok = newToken(token.STRING, l.readString()...)
-						}
-					} else {
-						tok = newToken(token.STRING, l.readString()...)
-					}
-				} else {
-					tok = newToken(token.STRING, l.readString()...)
-				}
-				l.startedUnderScoreToken = token.NONE
-			}
-
+		literal := l.readUnderScore()
+		if l.blockStart && len(literal) == 3 && l.skipTrailingBlanks() {
+			tok = newToken(token.HORIZON)
+		} else if l.isIntraword(position, l.nextPosition) {
+			// 単語の途中のアンダースコアは強調にならないので、文字列として読む
+			tok = newToken(token.STRING, l.readStringAfter(literal)...)
 		} else {
-			switch {
-			case isLineBreakCode(l.beforeCh):
-				literal := l.readUnderScore()
-				var tmpChs []byte
-				tmpChs = append(tmpChs, literal...)
-				tmpChs = append(tmpChs, SPACE)
-				if l.existsByEndOfLine(tmpChs) {
-					tokenType := token.GetUnderScoreToken(len(literal))
-					l.startedUnderScoreToken = tokenType
-					tok = newToken(tokenType)
-				} else if isLineBreakCode(l.peekNextChar()) && len(literal) == 3 {
-					tok = newToken(token.HORIZON)
-				} else {
-					tmpChs = nil
-					tmpChs = append(tmpChs, literal...)
-					tmpChs = append(tmpChs, LINE_BREAK_CODE_N)
-					if l.existsByEndOfLine(tmpChs) {
-						tokenType := token.GetUnderScoreToken(len(literal))
-						l.startedUnderScoreToken = tokenType
-						tok = newToken(tokenType)
-					} else {
-						l.startedUnderScoreToken = token.NONE
-						var tmpChs []byte
-						tmpChs = append(tmpChs, literal...)
-						tmpChs = append(tmpChs, l.readString()...)
-						tok = newToken(token.STRING, tmpChs...)
-					}
-				}
-			case isSpace(l.beforeCh):
-				literal := l.readUnderScore()
-				var tmpChs []byte
-				tmpChs = append(tmpChs, literal...)
-				tmpChs = append(tmpChs, SPACE)
-				if l.existsByEndOfLine(tmpChs) {
-					tokenType := token.GetUnderScoreToken(len(literal))
-					l.startedUnderScoreToken = tokenType
-					tok = newToken(tokenType)
-				} else {
-					tmpChs = nil
-					tmpChs = append(tmpChs, literal...)
-					tmpChs = append(tmpChs, LINE_BREAK_CODE_N)
-					if l.existsByEndOfLine(tmpChs) {
-						tokenType := token.GetUnderScoreToken(len(literal))
-						l.startedUnderScoreToken = tokenType
-						tok = newToken(tokenType)
-					} else {
-						l.startedUnderScoreToken = token.NONE
-						var tmpChs []byte
-						tmpChs = append(tmpChs, literal...)
-						tmpChs = append(tmpChs, l.readString()...)
-						tok = newToken(token.STRING, tmpChs...)
-					}
-				}
-			default:
-				l.startedUnderScoreToken = token.NONE
-				tok = newToken(token.STRING, l.readString()...)
-			}
+			tok = newToken(token.UNDER_SCORE, literal...)
 		}
-	case LBRACKET:
-		chs := l.untilLineFeedCode()
-		matchedChs := regexpUrl.Find(chs)
-		if matchedChs != nil {
-			l.startedLinkText = token.LINK_TEXT_BEGIN
-			tok = newToken(token.LINK_TEXT_BEGIN, l.currentCh)
+	case EXCLAMATION:
+		if l.peekNextChar() == LBRACKET {
+			// 画像の始まり
+			l.readChar()
+			tok = newToken(token.LBRACKET)
 		} else {
 			tok = newToken(token.STRING, l.readString()...)
 		}
+	case LBRACKET:
+		// リンクになるかどうかは、対応する括弧を見てパーサーが決める
+		tok = newToken(token.LBRACKET, l.currentCh)
 	case RBRACKET:
-		if l.startedLinkText == token.LINK_TEXT_BEGIN {
-			tok = newToken(token.LINK_TEXT_FINISH, l.currentCh)
-		} else {
-			tok = newToken(token.STRING, l.readString()...)
-		}
+		tok = newToken(token.RBRACKET, l.currentCh)
 	case LPAREN:
-		if l.startedLinkText == token.LINK_TEXT_BEGIN {
-			tok = newToken(token.LINK_BEGIN, l.currentCh)
+		tok = newToken(token.LPAREN, l.currentCh)
+	case RPAREN:
+		tok = newToken(token.RPAREN, l.currentCh)
+	case TILDE:
+		if l.blockStart && l.countRun(TILDE) >= 3 {
+			tok = newToken(token.CODE_FENCE, l.readRun(TILDE)...)
 		} else {
 			tok = newToken(token.STRING, l.readString()...)
 		}
-	case RPAREN:
-		if l.startedLinkText == token.LINK_TEXT_BEGIN {
-			l.startedLinkText = token.NONE
-			tok = newToken(token.LINK_FINISH, l.currentCh)
+	default:
+		if l.blockStart && l.isOrderedListMarker() {
+			// 番号付きリストの記号("1." や "1)")
+			for isDigit(l.peekNextChar()) {
+				l.readChar()
+			}
+			l.readChar()
+			tok = newToken(token.ORDERED_LIST_MARKER)
 		} else {
 			tok = newToken(token.STRING, l.readString()...)
 		}
-	case EOF:
-		tok = newToken(token.EOF)
+	}
+
+	// 記号のトークンにも、読み進めた分の文字列をそのまま持たせる
+	if tok.Literal == nil {
+		tok.Literal = l.input[position : l.currentPosition+1]
+	}
+
+	// 改行、引用記号、リストの記号の直後は、次のブロックが始まりうる
+	// 行頭のインデントは、その後ろもブロックの始まりとして読む
+	switch tok.Type {
+	case token.LINE_FEED_CODE, token.CITATION, token.HYPHEN, token.ORDERED_LIST_MARKER:
+		l.blockStart = true
+	case token.SPACE, token.TAB1, token.TAB2, token.TAB3:
 	default:
-		tok = newToken(token.STRING, l.readString()...)
+		l.blockStart = false
 	}
 
 	return tok
@@ -415,14 +296,18 @@ func newToken(tokenType token.TokenType, chs ...byte) token.Token {
 }
 
 func (l *Lexer) readChar() {
-	if l.currentPosition > 0 {
-		// 直前の文字をセット
-		l.beforeCh = l.currentCh
+	// 直前の文字をセット
+	l.beforeCh = l.currentCh
+
+	// 今の行を読み終えていれば、次の行を読み込む
+	if l.nextPosition >= len(l.input) && !l.eof {
+		l.readLine()
+		l.nextPosition = 0
 	}
 
 	// 次の文字が存在するか
 	if l.nextPosition >= len(l.input) {
-		// 次の文字は存在しない(まだ何も読み込んでいない or ファイルの終わり)
+		// 次の文字は存在しない(ファイルの終わり)
 		l.currentCh = 0
 	} else {
 		// 次の文字をセット
@@ -442,69 +327,33 @@ func (l *Lexer) peekNextChar() byte {
 	}
 }
 
-func (l *Lexer) peek2ndOrderChar() byte {
-	// 2つ次の文字を覗き見る
-	if l.nextPosition+1 >= len(l.input) {
-		return 0
-	} else {
-		return l.input[l.nextPosition+1]
+// isIntraword reports whether input[start:end] is between two characters that are
+// neither whitespace nor punctuation.
+func (l *Lexer) isIntraword(start, end int) bool {
+	if start == 0 || end >= len(l.input) {
+		return false
 	}
+	before, _ := utf8.DecodeLastRune(l.input[:start])
+	after, _ := utf8.DecodeRune(l.input[end:])
+	return !unicode.IsSpace(before) && !IsPunctuation(before) && !unicode.IsSpace(after) && !IsPunctuation(after)
 }
 
-func (l *Lexer) peek3ndOrderChar() byte {
-	// 3つ次の文字を覗き見る
-	if l.nextPosition+2 >= len(l.input) {
-		return 0
-	} else {
-		return l.input[l.nextPosition+2]
+// IsPunctuation reports whether r is an ASCII or Unicode punctuation character,
+// as used for the flanking rules of emphasis.
+func IsPunctuation(r rune) bool {
+	if r < utf8.RuneSelf {
+		return strings.ContainsRune("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", r)
 	}
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
 }
 
-func (l *Lexer) peek4ndOrderChar() byte {
-	// 4つ次の文字を覗き見る
-	if l.nextPosition+3 >= len(l.input) {
+func (l *Lexer) peek2ndOrderChar() byte {
+	// 2つ次の文字を覗き見る
+	if l.nextPosition+1 >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.nextPosition+3]
-	}
-}
-
-func (l *Lexer) existsByEndOfLine(chs []byte) bool {
-	// 現在の位置から次の改行コードでの文字を確認するだけなので、readCharは実行しない
-	position := l.currentPosition
-
-	var tmp []byte
-	for {
-		// ポジションを進める
-		position++
-		ch := l.input[position]
-		tmp = append(tmp, ch)
-		if bytes.Contains(tmp, chs) {
-			// 1つでも見つかればOK
-			return true
-		}
-		if isLineBreakCode(ch) {
-			break
-		}
-	}
-
-	return false
-}
-
-func (l *Lexer) twoBeforeChar() byte {
-	// 2つ前の文字を見る
-	if l.nextPosition < 3 {
-		return 0
-	}
-	return l.input[l.nextPosition-3]
-}
-
-func (l *Lexer) threeBeforeChar() byte {
-	// 3つ前の文字を見る
-	if l.nextPosition < 4 {
-		return 0
+		return l.input[l.nextPosition+1]
 	}
-	return l.input[l.nextPosition-4]
 }
 
 func isSharp(ch byte) bool {
@@ -527,6 +376,30 @@ func (l *Lexer) readHeading() []byte {
 	return l.input[position : l.currentPosition+1]
 }
 
+// skipTrailingBlanks は、行末まで空白とタブしかなければ読み飛ばして true を返す
+// (区切り線の後ろの空白は、区切り線に含める)
+func (l *Lexer) skipTrailingBlanks() bool {
+	end := l.nextPosition
+	for end < len(l.input) && (isSpace(l.input[end]) || isTab(l.input[end])) {
+		end++
+	}
+	if end < len(l.input) && !isLineBreakCode(l.input[end]) {
+		return false
+	}
+	for l.nextPosition < end {
+		l.readChar()
+	}
+	return true
+}
+
+// readStringAfter は、読み込んだ記号の literal に続く文字列を読み、合わせて1つの文字列として返す
+// (バッククォートなどの記号は、readString と同じく次のトークンにする)
+func (l *Lexer) readStringAfter(literal []byte) []byte {
+	position := l.currentPosition - len(literal) + 1
+	l.readString()
+	return l.input[position : l.currentPosition+1]
+}
+
 func (l *Lexer) readString() []byte {
 	position := l.currentPosition
 
@@ -537,71 +410,30 @@ func (l *Lexer) readString() []byte {
 		nextCh := l.peekNextChar()
 		var breakFlg bool
 		switch {
+		case l.nextPosition >= len(l.input):
+			// 行末を越えては読まない
+			breakFlg = true
 		case isSpace(nextCh), isLineBreakCode(nextCh):
 			breakFlg = true
-		case isBackQuote(nextCh):
-			peeked2ndOrderCh := l.peek2ndOrderChar()
-			if isSpace(peeked2ndOrderCh) || isLineBreakCode(peeked2ndOrderCh) {
-				breakFlg = true
-			}
-		case isAsterisk(nextCh):
-			switch l.startedAsteriskToken {
-			case token.ASTERISK_ITALIC_BEGIN:
-				peeked2ndOrderCh := l.peek2ndOrderChar()
-				if isSpace(peeked2ndOrderCh) || isLineBreakCode(peeked2ndOrderCh) {
-					breakFlg = true
-				}
-			case token.ASTERISK_BOLD_BEGIN:
-				peeked2ndOrderCh := l.peek2ndOrderChar()
-				if isAsterisk(peeked2ndOrderCh) {
-					peeked3ndOrderCh := l.peek3ndOrderChar()
-					if isSpace(peeked3ndOrderCh) || isLineBreakCode(peeked3ndOrderCh) {
-						breakFlg = true
-					}
-				}
-			case token.ASTERISK_ITALIC_BOLD_BEGIN:
-				peeked2ndOrderCh := l.peek2ndOrderChar()
-				if isAsterisk(peeked2ndOrderCh) {
-					peeked3ndOrderCh := l.peek3ndOrderChar()
-					if isAsterisk(peeked3ndOrderCh) {
-						peeked4ndOrderCh := l.peek4ndOrderChar()
-						if isSpace(peeked4ndOrderCh) || isLineBreakCode(peeked4ndOrderCh) {
-							breakFlg = true
-						}
-					}
-				}
-			}
+		case isBackQuote(nextCh), isAsterisk(nextCh):
+			breakFlg = true
 		case isUnderScore(nextCh):
-			switch l.startedUnderScoreToken {
-			case token.UNDER_SCORE_ITALIC_BEGIN:
-				peeked2ndOrderCh := l.peek2ndOrderChar()
-				if isSpace(peeked2ndOrderCh) || isLineBreakCode(peeked2ndOrderCh) {
-					breakFlg = true
-				}
-			case token.UNDER_SCORE_BOLD_BEGIN:
-				peeked2ndOrderCh := l.peek2ndOrderChar()
-				if isUnderScore(peeked2ndOrderCh) {
-					peeked3ndOrderCh := l.peek3ndOrderChar()
-					if isSpace(peeked3ndOrderCh) || isLineBreakCode(peeked3ndOrderCh) {
-						breakFlg = true
-					}
-				}
-			case token.UNDER_SCORE_ITALIC_BOLD_BEGIN:
-				peeked2ndOrderCh := l.peek2ndOrderChar()
-				if isUnderScore(peeked2ndOrderCh) {
-					peeked3ndOrderCh := l.peek3ndOrderChar()
-					if isUnderScore(peeked3ndOrderCh) {
-						peeked4ndOrderCh := l.peek4ndOrderChar()
-						if isSpace(peeked4ndOrderCh) || isLineBreakCode(peeked4ndOrderCh) {
-							breakFlg = true
-						}
-					}
-				}
+			// 単語の途中のアンダースコアは、連続する分をまとめて文字列に含める
+			end := l.nextPosition
+			for end < len(l.input) && isUnderScore(l.input[end]) {
+				end++
 			}
-		case isRightBracket(nextCh), isRightParen(nextCh):
-			if l.startedLinkText == token.LINK_TEXT_BEGIN {
+			if !l.isIntraword(l.nextPosition, end) {
 				breakFlg = true
+				break
+			}
+			for l.nextPosition < end-1 {
+				l.readChar()
 			}
+		case isBracket(nextCh):
+			breakFlg = true
+		case nextCh == EXCLAMATION:
+			breakFlg = l.peek2ndOrderChar() == LBRACKET
 		}
 
 		if breakFlg {
@@ -629,7 +461,8 @@ func (l *Lexer) readTab() []byte {
 
 	for {
 		nextCh := l.peekNextChar()
-		if !isTab(nextCh) {
+		// 4つ以上続くタブは、3つずつのトークンに分ける
+		if !isTab(nextCh) || l.currentPosition-position >= 2 {
 			break
 		}
 		// 文字が途切れるまで読み込む
@@ -688,16 +521,21 @@ func (l *Lexer) readUnderScore() []byte {
 	return l.input[position : l.currentPosition+1]
 }
 
-func isCitation(ch byte) bool {
-	return ch == GT
+func (l *Lexer) countRun(ch byte) int {
+	// 現在の位置から同じ文字がいくつ続くかを数えるだけなので、readCharは実行しない
+	cnt := 0
+	for position := l.currentPosition; position < len(l.input) && l.input[position] == ch; position++ {
+		cnt++
+	}
+	return cnt
 }
 
-func (l *Lexer) readCitation() []byte {
+func (l *Lexer) readRun(ch byte) []byte {
 	position := l.currentPosition
 
 	for {
 		nextCh := l.peekNextChar()
-		if !isCitation(nextCh) {
+		if nextCh != ch {
 			break
 		}
 		// 文字が途切れるまで読み込む
@@ -728,26 +566,28 @@ func (l *Lexer) readHyphen() []byte {
 	return l.input[position : l.currentPosition+1]
 }
 
-func isRightBracket(ch byte) bool {
-	return ch == RBRACKET
-}
-
-func isRightParen(ch byte) bool {
-	return ch == RPAREN
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
 }
 
-func (l *Lexer) untilLineFeedCode() []byte {
+// isOrderedListMarker reports whether the current character starts 1 to 9 digits
+// followed by `.` or `)` and then a space, a tab or the end of the line.
+func (l *Lexer) isOrderedListMarker() bool {
 	position := l.currentPosition
-
-	var chs []byte
-	for {
-		ch := l.input[position]
-		if ch == LINE_BREAK_CODE_N || ch == LINE_BREAK_CODE_R {
-			break
-		}
-		chs = append(chs, ch)
+	for position < len(l.input) && isDigit(l.input[position]) && position-l.currentPosition < 10 {
 		position++
 	}
+	digits := position - l.currentPosition
+	if digits == 0 || digits > 9 || position+1 >= len(l.input) {
+		return false
+	}
+	if l.input[position] != DOT && l.input[position] != RPAREN {
+		return false
+	}
+	nextCh := l.input[position+1]
+	return isSpace(nextCh) || isTab(nextCh) || isLineBreakCode(nextCh)
+}
 
-	return chs
+func isBracket(ch byte) bool {
+	return ch == LBRACKET || ch == RBRACKET || ch == LPAREN || ch == RPAREN
 }
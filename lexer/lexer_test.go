@@ -2,8 +2,11 @@ package lexer
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"testing/iotest"
 
 	"github.com/istsh/markdown-viewer/token"
 )
@@ -33,7 +36,7 @@ func compareGotAndWant(t *testing.T, goldenPath string, tests []expected) {
 		if tok.Type != tt.expectedType {
 			t.Errorf("tests[%d] - tokentype wrong. expected=%s, got=%s", i, tt.expectedType, tok.Type)
 		}
-		if tt.expectedType == token.STRING && !bytes.Equal(tok.Literal, []byte(tt.expectedLiteral)) {
+		if (tt.expectedType == token.STRING || tt.expectedLiteral != "") && !bytes.Equal(tok.Literal, []byte(tt.expectedLiteral)) {
 			t.Errorf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
 		}
 	}
@@ -230,15 +233,15 @@ func TestLexer3(t *testing.T) {
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "List3"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.CITATION1},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description1"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.CITATION1},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description2"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.CITATION1},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description3_1"},
 		{expectedType: token.SPACE},
@@ -246,11 +249,13 @@ func TestLexer3(t *testing.T) {
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description3_2"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.CITATION2},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description4"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.CITATION2},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description5"},
 		{expectedType: token.LINE_FEED_CODE},
@@ -267,39 +272,39 @@ func TestLexer4(t *testing.T) {
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.STRING, expectedLiteral: "Description1_1"},
 		{expectedType: token.SPACE},
-		{expectedType: token.BACK_QUOTE_BEGIN},
+		{expectedType: token.BACK_QUOTE, expectedLiteral: "`"},
 		{expectedType: token.STRING, expectedLiteral: "back"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "quote"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.BACK_QUOTE_FINISH},
+		{expectedType: token.BACK_QUOTE, expectedLiteral: "`"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description1_2"},
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.STRING, expectedLiteral: "Description2_1"},
 		{expectedType: token.SPACE},
-		{expectedType: token.ASTERISK_ITALIC_BEGIN},
+		{expectedType: token.ASTERISK, expectedLiteral: "*"},
 		{expectedType: token.STRING, expectedLiteral: "italic"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.ASTERISK_ITALIC_FINISH},
+		{expectedType: token.ASTERISK, expectedLiteral: "*"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description2_2"},
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.STRING, expectedLiteral: "Description3_1"},
 		{expectedType: token.SPACE},
-		{expectedType: token.ASTERISK_BOLD_BEGIN},
+		{expectedType: token.ASTERISK, expectedLiteral: "**"},
 		{expectedType: token.STRING, expectedLiteral: "bold"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.ASTERISK_BOLD_FINISH},
+		{expectedType: token.ASTERISK, expectedLiteral: "**"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description3_2"},
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.STRING, expectedLiteral: "Description4_1"},
 		{expectedType: token.SPACE},
-		{expectedType: token.ASTERISK_ITALIC_BOLD_BEGIN},
+		{expectedType: token.ASTERISK, expectedLiteral: "***"},
 		{expectedType: token.STRING, expectedLiteral: "italic"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "&"},
@@ -307,23 +312,23 @@ func TestLexer4(t *testing.T) {
 		{expectedType: token.STRING, expectedLiteral: "bold"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.ASTERISK_ITALIC_BOLD_FINISH},
+		{expectedType: token.ASTERISK, expectedLiteral: "***"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description4_2"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.ASTERISK_ITALIC_BEGIN},
+		{expectedType: token.ASTERISK, expectedLiteral: "*"},
 		{expectedType: token.STRING, expectedLiteral: "italic"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.ASTERISK_ITALIC_FINISH},
+		{expectedType: token.ASTERISK, expectedLiteral: "*"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.ASTERISK_BOLD_BEGIN},
+		{expectedType: token.ASTERISK, expectedLiteral: "**"},
 		{expectedType: token.STRING, expectedLiteral: "bold"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.ASTERISK_BOLD_FINISH},
+		{expectedType: token.ASTERISK, expectedLiteral: "**"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.ASTERISK_ITALIC_BOLD_BEGIN},
+		{expectedType: token.ASTERISK, expectedLiteral: "***"},
 		{expectedType: token.STRING, expectedLiteral: "italic"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "&"},
@@ -331,7 +336,7 @@ func TestLexer4(t *testing.T) {
 		{expectedType: token.STRING, expectedLiteral: "bold"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.ASTERISK_ITALIC_BOLD_FINISH},
+		{expectedType: token.ASTERISK, expectedLiteral: "***"},
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.EOF},
 	}
@@ -346,39 +351,39 @@ func TestLexer5(t *testing.T) {
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.STRING, expectedLiteral: "Description1_1"},
 		{expectedType: token.SPACE},
-		{expectedType: token.BACK_QUOTE_BEGIN},
+		{expectedType: token.BACK_QUOTE, expectedLiteral: "`"},
 		{expectedType: token.STRING, expectedLiteral: "back"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "quote"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.BACK_QUOTE_FINISH},
+		{expectedType: token.BACK_QUOTE, expectedLiteral: "`"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description1_2"},
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.STRING, expectedLiteral: "Description2_1"},
 		{expectedType: token.SPACE},
-		{expectedType: token.UNDER_SCORE_ITALIC_BEGIN},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "_"},
 		{expectedType: token.STRING, expectedLiteral: "italic"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.UNDER_SCORE_ITALIC_FINISH},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "_"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description2_2"},
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.STRING, expectedLiteral: "Description3_1"},
 		{expectedType: token.SPACE},
-		{expectedType: token.UNDER_SCORE_BOLD_BEGIN},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "__"},
 		{expectedType: token.STRING, expectedLiteral: "bold"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.UNDER_SCORE_BOLD_FINISH},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "__"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description3_2"},
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.STRING, expectedLiteral: "Description4_1"},
 		{expectedType: token.SPACE},
-		{expectedType: token.UNDER_SCORE_ITALIC_BOLD_BEGIN},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "___"},
 		{expectedType: token.STRING, expectedLiteral: "italic"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "&"},
@@ -386,23 +391,23 @@ func TestLexer5(t *testing.T) {
 		{expectedType: token.STRING, expectedLiteral: "bold"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.UNDER_SCORE_ITALIC_BOLD_FINISH},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "___"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "Description4_2"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.UNDER_SCORE_ITALIC_BEGIN},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "_"},
 		{expectedType: token.STRING, expectedLiteral: "italic"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.UNDER_SCORE_ITALIC_FINISH},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "_"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.UNDER_SCORE_BOLD_BEGIN},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "__"},
 		{expectedType: token.STRING, expectedLiteral: "bold"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.UNDER_SCORE_BOLD_FINISH},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "__"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.UNDER_SCORE_ITALIC_BOLD_BEGIN},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "___"},
 		{expectedType: token.STRING, expectedLiteral: "italic"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "&"},
@@ -410,7 +415,7 @@ func TestLexer5(t *testing.T) {
 		{expectedType: token.STRING, expectedLiteral: "bold"},
 		{expectedType: token.SPACE},
 		{expectedType: token.STRING, expectedLiteral: "area"},
-		{expectedType: token.UNDER_SCORE_ITALIC_BOLD_FINISH},
+		{expectedType: token.UNDER_SCORE, expectedLiteral: "___"},
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.EOF},
 	}
@@ -429,17 +434,356 @@ func TestLexer6(t *testing.T) {
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.HORIZON},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.LINK_TEXT_BEGIN},
+		{expectedType: token.LBRACKET, expectedLiteral: "["},
 		{expectedType: token.STRING, expectedLiteral: "Google"},
-		{expectedType: token.LINK_TEXT_FINISH},
-		{expectedType: token.LINK_BEGIN},
+		{expectedType: token.RBRACKET, expectedLiteral: "]"},
+		{expectedType: token.LPAREN, expectedLiteral: "("},
 		{expectedType: token.STRING, expectedLiteral: "https://www.google.com/"},
-		{expectedType: token.LINK_FINISH},
+		{expectedType: token.RPAREN, expectedLiteral: ")"},
 		{expectedType: token.LINE_FEED_CODE},
-		{expectedType: token.STRING, expectedLiteral: "[Google](https://www.google.com/"},
+		{expectedType: token.LBRACKET, expectedLiteral: "["},
+		{expectedType: token.STRING, expectedLiteral: "Google"},
+		{expectedType: token.RBRACKET, expectedLiteral: "]"},
+		{expectedType: token.LPAREN, expectedLiteral: "("},
+		{expectedType: token.STRING, expectedLiteral: "https://www.google.com/"},
 		{expectedType: token.LINE_FEED_CODE},
 		{expectedType: token.EOF},
 	}
 
 	compareGotAndWant(t, "../testdata/6.md.golden", tests)
 }
+
+func TestLexer7(t *testing.T) {
+	tests := []expected{
+		{expectedType: token.CITATION, expectedLiteral: ">"},
+		{expectedType: token.SPACE},
+		{expectedType: token.HEADING1},
+		{expectedType: token.STRING, expectedLiteral: "Heading1"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
+		{expectedType: token.SPACE},
+		{expectedType: token.HYPHEN},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "List1"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "Description1"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
+		{expectedType: token.CITATION, expectedLiteral: ">"},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "Description2"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.STRING, expectedLiteral: "Description3"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.EOF},
+	}
+
+	compareGotAndWant(t, "../testdata/7.md.golden", tests)
+}
+
+func TestLexer8(t *testing.T) {
+	tests := []expected{
+		{expectedType: token.LBRACKET, expectedLiteral: "!["},
+		{expectedType: token.STRING, expectedLiteral: "Logo"},
+		{expectedType: token.RBRACKET, expectedLiteral: "]"},
+		{expectedType: token.LPAREN, expectedLiteral: "("},
+		{expectedType: token.STRING, expectedLiteral: "logo.png"},
+		{expectedType: token.RPAREN, expectedLiteral: ")"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.LBRACKET, expectedLiteral: "["},
+		{expectedType: token.STRING, expectedLiteral: "Top"},
+		{expectedType: token.RBRACKET, expectedLiteral: "]"},
+		{expectedType: token.LPAREN, expectedLiteral: "("},
+		{expectedType: token.STRING, expectedLiteral: "#top"},
+		{expectedType: token.RPAREN, expectedLiteral: ")"},
+		{expectedType: token.SPACE},
+		{expectedType: token.LPAREN, expectedLiteral: "("},
+		{expectedType: token.STRING, expectedLiteral: "note"},
+		{expectedType: token.RPAREN, expectedLiteral: ")"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.EOF},
+	}
+
+	compareGotAndWant(t, "../testdata/8.md.golden", tests)
+}
+
+func TestLexer9(t *testing.T) {
+	tests := []expected{
+		{expectedType: token.HEADING1},
+		{expectedType: token.STRING, expectedLiteral: "Heading1"},
+		{expectedType: token.LINE_FEED_CODE, expectedLiteral: "\r\n"},
+		{expectedType: token.LINE_FEED_CODE, expectedLiteral: "\r\n"},
+		{expectedType: token.STRING, expectedLiteral: "Description1"},
+		{expectedType: token.LINE_FEED_CODE, expectedLiteral: "\r\n"},
+		{expectedType: token.STRING, expectedLiteral: "Description2"},
+		{expectedType: token.LINE_FEED_CODE, expectedLiteral: "\r"},
+		{expectedType: token.STRING, expectedLiteral: "Description3"},
+		{expectedType: token.LINE_FEED_CODE, expectedLiteral: "\r"},
+		{expectedType: token.LINE_FEED_CODE, expectedLiteral: "\r"},
+		{expectedType: token.HYPHEN},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "List1"},
+		{expectedType: token.LINE_FEED_CODE, expectedLiteral: "\r\n"},
+		{expectedType: token.HYPHEN},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "List2"},
+		{expectedType: token.LINE_FEED_CODE, expectedLiteral: "\n"},
+		{expectedType: token.EOF},
+	}
+
+	compareGotAndWant(t, "../testdata/9.md.golden", tests)
+}
+
+func TestLexer10(t *testing.T) {
+	tests := []expected{
+		{expectedType: token.HYPHEN},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "List1"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.SPACE},
+		{expectedType: token.SPACE},
+		{expectedType: token.HYPHEN},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "List1_1"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.SPACE},
+		{expectedType: token.SPACE},
+		{expectedType: token.SPACE},
+		{expectedType: token.SPACE},
+		{expectedType: token.HYPHEN},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "List1_1_1"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.TAB3, expectedLiteral: "\t\t\t"},
+		{expectedType: token.TAB1, expectedLiteral: "\t"},
+		{expectedType: token.HYPHEN},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "List1_1_1_1"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.EOF},
+	}
+
+	compareGotAndWant(t, "../testdata/10.md.golden", tests)
+}
+
+func TestLexer11(t *testing.T) {
+	tests := []expected{
+		{expectedType: token.ORDERED_LIST_MARKER, expectedLiteral: "1."},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "List1"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.ORDERED_LIST_MARKER, expectedLiteral: "2)"},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "List2"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.SPACE},
+		{expectedType: token.SPACE},
+		{expectedType: token.SPACE},
+		{expectedType: token.ORDERED_LIST_MARKER, expectedLiteral: "10."},
+		{expectedType: token.SPACE},
+		{expectedType: token.STRING, expectedLiteral: "List2_1"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.STRING, expectedLiteral: "3.x"},
+		{expectedType: token.LINE_FEED_CODE},
+		{expectedType: token.EOF},
+	}
+
+	compareGotAndWant(t, "../testdata/11.md.golden", tests)
+}
+
+func TestBlockStart(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		// the symbols after `#` or `-` that aren't a marker are read as tokens of their own
+		{input: "#`a`", want: []string{`1:1 STRING "#"`, `1:2 BACK_QUOTE "` + "`" + `"`, `1:3 STRING "a"`, `1:4 BACK_QUOTE "` + "`" + `"`}},
+		{input: "-*a*", want: []string{`1:1 STRING "-"`, `1:2 ASTERISK "*"`, `1:3 STRING "a"`, `1:4 ASTERISK "*"`}},
+		{input: "#Heading", want: []string{`1:1 STRING "#Heading"`}},
+		{input: "#\tHeading", want: []string{`1:1 HEADING1 "#\t"`, `1:3 STRING "Heading"`}},
+		// a thematic break can be followed by blanks
+		{input: "--- \t", want: []string{`1:1 HORIZON "--- \t"`}},
+		{input: "*** a", want: []string{`1:1 ASTERISK "***"`, `1:4 SPACE " "`, `1:5 STRING "a"`}},
+	}
+
+	for i, tt := range tests {
+		l := New([]byte(tt.input))
+		for j, want := range tt.want {
+			if got := l.NextToken().String(); got != want {
+				t.Errorf("tests[%d][%d] - token wrong. expected=%s, got=%s", i, j, want, got)
+			}
+		}
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "Description1\nDescription2\r\n", want: "\n"},
+		{input: "Description1\r\nDescription2\n", want: "\r\n"},
+		{input: "Description1\rDescription2\r", want: "\r"},
+		{input: "Description1", want: "\n"},
+	}
+
+	for i, tt := range tests {
+		l := New([]byte(tt.input))
+		for l.NextToken().Type != token.EOF {
+		}
+		if got := string(l.LineEnding()); got != tt.want {
+			t.Errorf("tests[%d] - line ending wrong. expected=%q, got=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	input := "# 見出し\r\n- *a*\rb"
+	tests := []string{
+		`1:1 HEADING1 "# "`,
+		`1:3 STRING "見出し"`,
+		`1:6 LINE_FEED_CODE "\r\n"`,
+		`2:1 HYPHEN "-"`,
+		`2:2 SPACE " "`,
+		`2:3 ASTERISK "*"`,
+		`2:4 STRING "a"`,
+		`2:5 ASTERISK "*"`,
+		`2:6 LINE_FEED_CODE "\r"`,
+		`3:1 STRING "b"`,
+		// the line feed added to the end of the input takes no space
+		`3:2 LINE_FEED_CODE "\n"`,
+		`3:2 EOF ""`,
+	}
+
+	l := New([]byte(input))
+	for i, want := range tests {
+		if got := l.NextToken().String(); got != want {
+			t.Errorf("tests[%d] - token wrong. expected=%s, got=%s", i, want, got)
+		}
+	}
+
+	// the offset counts bytes
+	l = New([]byte(input))
+	l.NextToken()
+	l.NextToken()
+	if got := l.NextToken().Pos.Offset; got != 11 {
+		t.Errorf("offset wrong. expected=%d, got=%d", 11, got)
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	goldenPaths, err := filepath.Glob("../testdata/*.md.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, goldenPath := range goldenPaths {
+		input, err := ioutil.ReadFile(goldenPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := New(input)
+		got := NewReader(iotest.OneByteReader(bytes.NewReader(input)))
+		for i := 0; ; i++ {
+			wantTok := want.NextToken()
+			gotTok := got.NextToken()
+			if gotTok.Type != wantTok.Type || !bytes.Equal(gotTok.Literal, wantTok.Literal) {
+				t.Fatalf("%s: tokens[%d] wrong. expected=%s(%q), got=%s(%q)", goldenPath, i, wantTok.Type, wantTok.Literal, gotTok.Type, gotTok.Literal)
+			}
+			if wantTok.Type == token.EOF {
+				break
+			}
+		}
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	l := NewReader(iotest.TimeoutReader(bytes.NewReader([]byte("# Heading1\nDescription1\n"))))
+	for l.NextToken().Type != token.EOF {
+	}
+
+	if l.Err() != iotest.ErrTimeout {
+		t.Errorf("error wrong. expected=%v, got=%v", iotest.ErrTimeout, l.Err())
+	}
+}
+
+// benchmarkInputs are single-line inputs that used to make the lexer scan the rest of the line repeatedly.
+var benchmarkInputs = []struct {
+	name string
+	unit string
+}{
+	{name: "Text", unit: "word "},
+	{name: "Emphasis", unit: "*a* __b__ "},
+	{name: "Intraword", unit: "snake_case_"},
+	{name: "CodeSpan", unit: "`a` "},
+	{name: "Link", unit: "[a](b) "},
+	{name: "Bracket", unit: "[a]("},
+}
+
+func BenchmarkLexer(b *testing.B) {
+	for _, bi := range benchmarkInputs {
+		for _, size := range []int{1 << 16, 1 << 18, 1 << 20} {
+			input := bytes.Repeat([]byte(bi.unit), size/len(bi.unit))
+			b.Run(fmt.Sprintf("%s/%dKB", bi.name, size>>10), func(b *testing.B) {
+				b.SetBytes(int64(len(input)))
+				for i := 0; i < b.N; i++ {
+					l := New(input)
+					for l.NextToken().Type != token.EOF {
+					}
+				}
+			})
+		}
+	}
+}
+
+func FuzzNextToken(f *testing.F) {
+	goldenPaths, err := filepath.Glob("../testdata/*.md.golden")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, goldenPath := range goldenPaths {
+		input, err := ioutil.ReadFile(goldenPath)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(input)
+	}
+	// inputs that used to read past the end of the input forever
+	f.Add([]byte("-"))
+	f.Add([]byte("Description1\n#"))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		// every token but EOF consumes at least one byte, and a line feed may be added at the end
+		maxTokens := len(input) + 1
+
+		var literals []byte
+		l := New(input)
+		for i := 0; ; i++ {
+			if i > maxTokens {
+				t.Fatalf("more than %d tokens for %d bytes", maxTokens, len(input))
+			}
+			tok := l.NextToken()
+			if tok.Type == token.EOF {
+				break
+			}
+			if len(tok.Literal) == 0 {
+				t.Fatalf("tokens[%d] - empty literal. type=%s", i, tok.Type)
+			}
+			literals = append(literals, tok.Literal...)
+		}
+
+		// the literals of the tokens are the input itself
+		want := input
+		if len(want) > 0 && want[len(want)-1] != '\n' && want[len(want)-1] != '\r' {
+			want = append(want[:len(want):len(want)], '\n')
+		}
+		if !bytes.Equal(literals, want) {
+			t.Errorf("literals wrong. expected=%q, got=%q", want, literals)
+		}
+	})
+}
@@ -1,48 +1,54 @@
 package main
 
 import (
-	"encoding/json"
-	"io/ioutil"
-	"net/http"
-
-	"github.com/istsh/markdown-viewer/lexer"
-	"github.com/istsh/markdown-viewer/parser"
+	"fmt"
+	"os"
+	"strings"
 )
 
-type Input struct {
-	Markdown string `json:"markdown"`
+// commands are the subcommands of markdown-viewer by name.
+var commands = map[string]func(args []string) error{
+	"serve":  serve,
+	"view":   view,
+	"render": render,
+	"tokens": tokens,
+	"ast":    printAST,
+	"fmt":    formatFiles,
+	"epub":   makeBook,
 }
 
+const usage = `usage: markdown-viewer <command> [flags] [args]
+
+commands:
+  serve               start the HTTP server (the default)
+  view FILE           show a markdown file in the terminal
+  render FILE|-...    convert markdown to html, text, latex, man, docx, ...
+  tokens FILE|-...    dump the tokens of the lexer
+  ast FILE|-...       dump the document tree as JSON
+  fmt FILE|-...       format markdown files
+  epub FILE|DIR...    make an EPUB book of markdown files
+
+Run markdown-viewer <command> -h for the flags of a command.
+`
+
 func main() {
-	http.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "GET" {
-			w.WriteHeader(http.StatusOK)
-		}
-	})
-	http.HandleFunc("/parse", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			body, err := ioutil.ReadAll(r.Body)
-			if err != nil {
-				panic(err)
-			}
-
-			input := &Input{}
-			if err := json.Unmarshal(body, input); err != nil {
-				panic(err)
-			}
-
-			inputBytes := []byte(input.Markdown)
-
-			l := lexer.New(inputBytes)
-			p := parser.New(l)
-			result := p.Parse()
-
-			w.Header().Set("Content-Type", "application/json")
-			w.Write(result)
-		} else {
-			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
-		}
-	})
-
-	http.ListenAndServe(":8080", nil)
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		fmt.Print(usage)
+		return
+	}
+
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "markdown-viewer: unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	if err := command(args); err != nil {
+		fmt.Fprintln(os.Stderr, "markdown-viewer:", err)
+		os.Exit(1)
+	}
 }
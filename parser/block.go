@@ -0,0 +1,452 @@
+package parser
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/token"
+)
+
+// block is an open block of the document tree and the state needed to continue it.
+type block struct {
+	node *ast.Node
+
+	// children are the blocks opened inside this one.
+	children []*block
+
+	// tokens is the inline content of a PARAGRAPH or HEADING.
+	tokens []token.Token
+
+	// content is the text of a CODE_BLOCK.
+	content []byte
+
+	// rows of a TABLE, starting with the header, and the alignments of its columns.
+	rows       []tableRow
+	alignments []ast.Alignment
+
+	// markerOffset and padding of a LIST_ITEM;
+	// a line continues the item when it is indented by at least markerOffset+padding.
+	markerOffset int
+	padding      int
+
+	// fence of a fenced CODE_BLOCK.
+	fenced      bool
+	fenceChar   byte
+	fenceLength int
+	fenceOffset int
+
+	// startLine and endLine are the first and the last line of the block, counted from 1.
+	startLine int
+	endLine   int
+}
+
+// continuation is the result of matching a line against an open block.
+type continuation int
+
+const (
+	notMatched continuation = iota
+	matched
+	// lineDone means the open block consumed the whole line.
+	lineDone
+)
+
+func (p *Parser) tip() *block {
+	return p.open[len(p.open)-1]
+}
+
+// processLine incorporates a line into the document tree.
+func (p *Parser) processLine(ln *line) {
+	// 1. Match the line against the open blocks.
+	lastMatched := 1
+matching:
+	for ; lastMatched < len(p.open); lastMatched++ {
+		switch p.continues(p.open[lastMatched], ln) {
+		case notMatched:
+			break matching
+		case lineDone:
+			return
+		}
+	}
+
+	allClosed := lastMatched == len(p.open)
+	closeUnmatched := func() {
+		if !allClosed {
+			p.closeBlocks(lastMatched)
+			allClosed = true
+		}
+	}
+
+	// 2. Look for new block starts.
+	container := p.open[lastMatched-1]
+	if container.node.Type == ast.PARAGRAPH && p.startTable(container, ln) {
+		return
+	}
+	for container.node.Type != ast.CODE_BLOCK {
+		indent := ln.indent()
+		if indent >= 4 {
+			if tip := p.tip().node.Type; tip == ast.PARAGRAPH || tip == ast.TABLE || ln.blank() {
+				// an indented line can't interrupt a paragraph or a table
+				break
+			}
+			// the indentation is a part of an indented code block
+			start := ln.pos()
+			ln.skipColumns(4)
+			closeUnmatched()
+			container = p.addChild(ast.CODE_BLOCK, start)
+			break
+		}
+
+		ln.skipColumns(indent)
+		start := ln.pos()
+		tok, ok := ln.token()
+		if !ok {
+			break
+		}
+
+		switch tok.Type {
+		case token.CITATION:
+			ln.advance()
+			// the marker may be followed by one optional space
+			ln.skipColumns(1)
+			closeUnmatched()
+			container = p.addChild(ast.BLOCK_QUOTE, start)
+			container.node.End = ln.end
+			continue
+		case token.HEADING1, token.HEADING2, token.HEADING3, token.HEADING4, token.HEADING5, token.HEADING6:
+			ln.advance()
+			closeUnmatched()
+			b := p.addChild(ast.HEADING, start)
+			b.node.Level = headingLevel(tok.Type)
+			b.tokens = trimTokens(ln.rest())
+			p.closeLeaf(ln)
+			return
+		case token.CODE_FENCE:
+			info := bytes.TrimSpace(tokensBytes(ln.rest()[1:]))
+			if tok.Literal[0] == '`' && bytes.IndexByte(info, '`') >= 0 {
+				break
+			}
+			closeUnmatched()
+			container = p.addChild(ast.CODE_BLOCK, start)
+			container.node.End = ln.end
+			container.fenced = true
+			container.fenceChar = tok.Literal[0]
+			container.fenceLength = len(tok.Literal)
+			container.fenceOffset = indent
+			container.node.Info = info
+			return
+		case token.HORIZON:
+			ln.advance()
+			closeUnmatched()
+			p.addChild(ast.HORIZON, start)
+			p.closeLeaf(ln)
+			return
+		case token.HYPHEN, token.ORDERED_LIST_MARKER:
+			list := newList(tok)
+			saved := *ln
+			ln.advance()
+			if container.node.Type == ast.PARAGRAPH && (ln.blank() || (list.Ordered && list.Start != 1)) {
+				// an empty list item, or a list starting with a number other than 1, can't interrupt a paragraph
+				*ln = saved
+				break
+			}
+			markerWidth := len(tok.Literal)
+			padding := markerWidth + ln.indent()
+			if padding > markerWidth+4 || ln.blank() {
+				// the content starts with an indented code block, or the item is empty
+				padding = markerWidth + 1
+			}
+			ln.skipColumns(padding - markerWidth)
+			closeUnmatched()
+			if tip := p.tip().node; tip.Type != ast.LIST || tip.Ordered != list.Ordered || tip.Delimiter != list.Delimiter {
+				// a change of the marker starts a new list
+				p.addNode(list, start)
+			}
+			container = p.addChild(ast.LIST_ITEM, start)
+			container.node.End = ln.end
+			container.markerOffset = indent
+			container.padding = padding
+			continue
+		}
+		break
+	}
+
+	// 3. Add the rest of the line to the innermost block.
+	if !allClosed && !ln.blank() && p.tip().node.Type == ast.PARAGRAPH {
+		// lazy continuation line
+		p.addLine(p.tip(), ln)
+		return
+	}
+
+	closeUnmatched()
+	switch {
+	case container.node.Type == ast.CODE_BLOCK:
+		p.addLine(container, ln)
+	case ln.blank():
+	case container.node.Type == ast.TABLE:
+		p.addLine(container, ln)
+	case container.node.Type == ast.PARAGRAPH:
+		p.addLine(container, ln)
+	default:
+		ln.skipIndent()
+		p.addLine(p.addChild(ast.PARAGRAPH, ln.pos()), ln)
+	}
+}
+
+// continues matches ln against the open block b and consumes its markers.
+func (p *Parser) continues(b *block, ln *line) continuation {
+	switch b.node.Type {
+	case ast.BLOCK_QUOTE:
+		indent := ln.indent()
+		if indent >= 4 {
+			return notMatched
+		}
+		saved := *ln
+		ln.skipColumns(indent)
+		if tok, ok := ln.token(); ok && tok.Type == token.CITATION {
+			ln.advance()
+			ln.skipColumns(1)
+			b.node.End = ln.end
+			return matched
+		}
+		*ln = saved
+		return notMatched
+	case ast.LIST:
+		return matched
+	case ast.LIST_ITEM:
+		if ln.blank() {
+			if len(b.node.Children) == 0 {
+				// an item that starts with a blank line ends at the next blank line
+				return notMatched
+			}
+			ln.skipIndent()
+			return matched
+		}
+		if ln.indent() >= b.markerOffset+b.padding {
+			ln.skipColumns(b.markerOffset + b.padding)
+			return matched
+		}
+		return notMatched
+	case ast.CODE_BLOCK:
+		if b.fenced {
+			indent := ln.indent()
+			if indent < 4 {
+				saved := *ln
+				ln.skipColumns(indent)
+				tok, ok := ln.token()
+				if ok && tok.Type == token.CODE_FENCE && tok.Literal[0] == b.fenceChar && len(tok.Literal) >= b.fenceLength {
+					ln.advance()
+					if ln.blank() {
+						p.closeLeaf(ln)
+						return lineDone
+					}
+				}
+				*ln = saved
+			}
+			ln.skipColumns(b.fenceOffset)
+			return matched
+		}
+		if ln.indent() >= 4 {
+			ln.skipColumns(4)
+			return matched
+		}
+		if ln.blank() {
+			ln.skipIndent()
+			return matched
+		}
+		return notMatched
+	case ast.PARAGRAPH, ast.TABLE:
+		if ln.blank() {
+			return notMatched
+		}
+		return matched
+	default:
+		return notMatched
+	}
+}
+
+// addChild opens a new block of type t starting at start, closing open blocks until one can contain it.
+func (p *Parser) addChild(t ast.NodeType, start token.Position) *block {
+	return p.addNode(ast.NewNode(t), start)
+}
+
+// addNode opens a new block for node starting at start, closing open blocks until one can contain it.
+func (p *Parser) addNode(node *ast.Node, start token.Position) *block {
+	node.Pos = start
+	for !canContain(p.tip().node.Type, node.Type) {
+		p.closeBlocks(len(p.open) - 1)
+	}
+
+	b := &block{node: node, startLine: p.lineNumber}
+	p.tip().node.AppendChild(b.node)
+	p.tip().children = append(p.tip().children, b)
+	p.open = append(p.open, b)
+	return b
+}
+
+// addLine adds the rest of ln to the content of the leaf block b.
+func (p *Parser) addLine(b *block, ln *line) {
+	if b.node.Type == ast.CODE_BLOCK {
+		if b.fenced || !ln.blank() {
+			// trailing blank lines are not part of an indented code block
+			b.node.End = ln.end
+		}
+		b.content = append(b.content, ln.restBytes()...)
+		b.content = append(b.content, '\n')
+		return
+	}
+
+	b.node.End = ln.end
+	ln.skipIndent()
+	if b.node.Type == ast.TABLE {
+		b.rows = append(b.rows, tableRow{tokens: trimTokens(ln.rest()), end: ln.end})
+		return
+	}
+	if len(b.tokens) > 0 {
+		b.tokens = append(b.tokens, token.Token{Type: token.LINE_FEED_CODE, Literal: []byte{'\n'}, Pos: b.tokens[len(b.tokens)-1].End()})
+	}
+	start := len(b.tokens)
+	b.tokens = ln.appendRest(b.tokens)
+	b.tokens = append(b.tokens[:start], trimTokens(b.tokens[start:])...)
+}
+
+// closeLeaf finalizes the innermost block, which ends at the line ln.
+func (p *Parser) closeLeaf(ln *line) {
+	p.tip().endLine = p.lineNumber
+	p.tip().node.End = ln.end
+	p.closeBlocks(len(p.open) - 1)
+}
+
+// closeBlocks finalizes the open blocks until only n of them remain.
+// Unless already set, a block ends at the line before the current one.
+func (p *Parser) closeBlocks(n int) {
+	for len(p.open) > n {
+		b := p.tip()
+		p.open = p.open[:len(p.open)-1]
+		if b.endLine == 0 {
+			b.endLine = p.lineNumber - 1
+		}
+		p.finalize(b)
+
+		if p.emit != nil && len(p.open) == 1 {
+			// the block is finished, so hand it over and forget it
+			if p.err == nil {
+				p.err = p.emit(b.node)
+			}
+			doc := p.open[0]
+			doc.node.Children = doc.node.Children[:0]
+			doc.children = doc.children[:0]
+		}
+	}
+}
+
+func (p *Parser) finalize(b *block) {
+	switch b.node.Type {
+	case ast.BLOCK_QUOTE:
+		// a lazy continuation line of a paragraph ends the quote after its last marker
+		if last := b.node.LastChild(); last != nil && last.End.Offset > b.node.End.Offset {
+			b.node.End = last.End
+		}
+	case ast.LIST_ITEM:
+		// an item ends with its last child, not with the blank lines after it
+		if len(b.children) > 0 {
+			b.endLine = b.children[len(b.children)-1].endLine
+			b.node.End = b.node.LastChild().End
+		} else {
+			b.endLine = b.startLine
+		}
+	case ast.LIST:
+		b.endLine = b.children[len(b.children)-1].endLine
+		b.node.End = b.node.LastChild().End
+		// a list is loose if any of its items, or any of the blocks directly in an item, are separated by a blank line
+		b.node.Tight = !separatedByBlankLine(b.children)
+		for _, item := range b.children {
+			if separatedByBlankLine(item.children) {
+				b.node.Tight = false
+			}
+		}
+	case ast.PARAGRAPH, ast.HEADING:
+		parseInlines(b.node, b.tokens)
+	case ast.TABLE:
+		finalizeTable(b)
+	case ast.CODE_BLOCK:
+		content := b.content
+		if !b.fenced {
+			// trailing blank lines are not part of an indented code block
+			for bytes.HasSuffix(content, []byte("\n\n")) {
+				content = content[:len(content)-1]
+			}
+		}
+		b.node.Literal = content
+	}
+}
+
+// newList returns a LIST node for the list marker tok.
+func newList(tok token.Token) *ast.Node {
+	list := ast.NewNode(ast.LIST)
+	if tok.Type == token.ORDERED_LIST_MARKER {
+		list.Ordered = true
+		list.Delimiter = tok.Literal[len(tok.Literal)-1]
+		list.Start, _ = strconv.Atoi(string(tok.Literal[:len(tok.Literal)-1]))
+	} else {
+		list.Delimiter = tok.Literal[0]
+	}
+	return list
+}
+
+// separatedByBlankLine reports whether there is a blank line between any two of blocks.
+func separatedByBlankLine(blocks []*block) bool {
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i-1].endLine != blocks[i].startLine-1 {
+			return true
+		}
+	}
+	return false
+}
+
+func canContain(parent, child ast.NodeType) bool {
+	switch parent {
+	case ast.DOCUMENT, ast.BLOCK_QUOTE, ast.LIST_ITEM:
+		return child != ast.LIST_ITEM
+	case ast.LIST:
+		return child == ast.LIST_ITEM
+	default:
+		return false
+	}
+}
+
+func headingLevel(t token.TokenType) int {
+	switch t {
+	case token.HEADING1:
+		return 1
+	case token.HEADING2:
+		return 2
+	case token.HEADING3:
+		return 3
+	case token.HEADING4:
+		return 4
+	case token.HEADING5:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// trimTokens removes the whitespace tokens at both ends of tokens.
+func trimTokens(tokens []token.Token) []token.Token {
+	for len(tokens) > 0 && isWhitespaceOnly(tokens[0].Literal) {
+		tokens = tokens[1:]
+	}
+	for len(tokens) > 0 && isWhitespaceOnly(tokens[len(tokens)-1].Literal) {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
+
+func tokensBytes(tokens []token.Token) []byte {
+	var chs []byte
+	for _, tok := range tokens {
+		chs = append(chs, tok.Literal...)
+	}
+	return chs
+}
@@ -0,0 +1,446 @@
+package parser
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/token"
+)
+
+// item is an entry of the list of inlines being built.
+type item struct {
+	node       *ast.Node
+	prev, next *item
+
+	// plain is true for a text that no delimiter or bracket refers to, which the text after it can be added to.
+	// owned is true once the literal of such a text is a copy, no longer sharing its array with the input.
+	plain bool
+	owned bool
+}
+
+// delimiter is an entry of the delimiter stack: a run of `*` or `_` that may open or close emphasis.
+type delimiter struct {
+	item *item
+	ch   byte
+
+	// numDelims is the number of characters left in the run, origDelims is the length of the run.
+	numDelims  int
+	origDelims int
+
+	canOpen  bool
+	canClose bool
+
+	prev, next *delimiter
+}
+
+// bracket is an opened link text or image description.
+type bracket struct {
+	item  *item
+	image bool
+	// active is false once the bracket is inside a link, since links can't contain other links.
+	active bool
+	// delimiters is the top of the delimiter stack when the bracket was opened.
+	delimiters *delimiter
+	prev       *bracket
+}
+
+// inlineParser builds the inline nodes of a leaf block from its tokens.
+type inlineParser struct {
+	tokens []token.Token
+	pos    int
+
+	head, tail *item
+	delimiters *delimiter // top of the delimiter stack
+	brackets   *bracket   // top of the bracket stack
+
+	// backQuotes holds the indexes of the runs of backticks that are not yet passed, by the length of the run.
+	// It is built on the first code span, so each closer is looked for only once.
+	backQuotes map[int][]int
+}
+
+// parseInlines builds the inline children of parent from tokens.
+func parseInlines(parent *ast.Node, tokens []token.Token) {
+	ip := &inlineParser{tokens: tokens}
+	for ; ip.pos < len(ip.tokens); ip.pos++ {
+		ip.parseToken()
+	}
+	ip.processEmphasis(nil)
+
+	appendItems(parent, ip.head, nil)
+}
+
+func (ip *inlineParser) parseToken() {
+	tok := ip.tokens[ip.pos]
+	switch tok.Type {
+	case token.ASTERISK, token.UNDER_SCORE:
+		ip.parseDelimiterRun(tok)
+	case token.BACK_QUOTE:
+		ip.parseCodeSpan(tok)
+	case token.CODE_FENCE:
+		// a fence that didn't start a code block is an ordinary run of backticks or tildes
+		if tok.Literal[0] == '`' {
+			ip.parseCodeSpan(tok)
+		} else {
+			ip.appendPlainText(tok)
+		}
+	case token.LBRACKET:
+		ip.brackets = &bracket{
+			item:       ip.appendText(tok),
+			image:      tok.Literal[0] == '!',
+			active:     true,
+			delimiters: ip.delimiters,
+			prev:       ip.brackets,
+		}
+	case token.RBRACKET:
+		ip.parseCloseBracket(tok)
+	case token.LINE_FEED_CODE:
+		node := ast.NewNode(ast.SOFT_BREAK)
+		node.Pos = tok.Pos
+		node.End = tok.End()
+		ip.append(node)
+	default:
+		ip.appendPlainText(tok)
+	}
+}
+
+// parseDelimiterRun adds a run of `*` or `_` as text and pushes it onto the delimiter stack.
+func (ip *inlineParser) parseDelimiterRun(tok token.Token) {
+	before := ip.runeBefore()
+	after := ip.runeAfter()
+
+	leftFlanking := !unicode.IsSpace(after) &&
+		(!lexer.IsPunctuation(after) || unicode.IsSpace(before) || lexer.IsPunctuation(before))
+	rightFlanking := !unicode.IsSpace(before) &&
+		(!lexer.IsPunctuation(before) || unicode.IsSpace(after) || lexer.IsPunctuation(after))
+
+	d := &delimiter{
+		item:       ip.appendText(tok),
+		ch:         tok.Literal[0],
+		numDelims:  len(tok.Literal),
+		origDelims: len(tok.Literal),
+	}
+	if d.ch == '*' {
+		d.canOpen = leftFlanking
+		d.canClose = rightFlanking
+	} else {
+		// `_` can't open or close emphasis inside a word
+		d.canOpen = leftFlanking && (!rightFlanking || lexer.IsPunctuation(before))
+		d.canClose = rightFlanking && (!leftFlanking || lexer.IsPunctuation(after))
+	}
+	if !d.canOpen && !d.canClose {
+		return
+	}
+
+	d.prev = ip.delimiters
+	if d.prev != nil {
+		d.prev.next = d
+	}
+	ip.delimiters = d
+}
+
+// runeBefore returns the character before the current token; the start of the block counts as a line break.
+func (ip *inlineParser) runeBefore() rune {
+	if ip.pos == 0 {
+		return '\n'
+	}
+	r, _ := utf8.DecodeLastRune(ip.tokens[ip.pos-1].Literal)
+	return r
+}
+
+// runeAfter returns the character after the current token; the end of the block counts as a line break.
+func (ip *inlineParser) runeAfter() rune {
+	if ip.pos+1 >= len(ip.tokens) {
+		return '\n'
+	}
+	r, _ := utf8.DecodeRune(ip.tokens[ip.pos+1].Literal)
+	return r
+}
+
+// parseCodeSpan looks for a closing run of backticks of the same length as opener
+// and takes the tokens in between verbatim.
+func (ip *inlineParser) parseCodeSpan(opener token.Token) {
+	if ip.backQuotes == nil {
+		ip.backQuotes = map[int][]int{}
+		for i, tok := range ip.tokens {
+			if isBackQuoteRun(tok) {
+				ip.backQuotes[len(tok.Literal)] = append(ip.backQuotes[len(tok.Literal)], i)
+			}
+		}
+	}
+
+	closers := ip.backQuotes[len(opener.Literal)]
+	for len(closers) > 0 && closers[0] <= ip.pos {
+		closers = closers[1:]
+	}
+	ip.backQuotes[len(opener.Literal)] = closers
+
+	if len(closers) > 0 {
+		end := closers[0]
+
+		var literal []byte
+		for _, tok := range ip.tokens[ip.pos+1 : end] {
+			if tok.Type == token.LINE_FEED_CODE {
+				// line endings are converted to spaces
+				literal = append(literal, ' ')
+			} else {
+				literal = append(literal, tok.Literal...)
+			}
+		}
+		// one space is stripped from both ends, unless the content consists only of spaces
+		if len(literal) >= 2 && literal[0] == ' ' && literal[len(literal)-1] == ' ' && !isWhitespaceOnly(literal) {
+			literal = literal[1 : len(literal)-1]
+		}
+
+		node := ast.NewNode(ast.CODE)
+		node.Literal = literal
+		node.Pos = opener.Pos
+		node.End = ip.tokens[end].End()
+		ip.append(node)
+		ip.pos = end
+		return
+	}
+	ip.appendText(opener)
+}
+
+func isBackQuoteRun(tok token.Token) bool {
+	return tok.Type == token.BACK_QUOTE || (tok.Type == token.CODE_FENCE && tok.Literal[0] == '`')
+}
+
+// parseCloseBracket turns the inlines after the innermost bracket into a LINK or an IMAGE
+// when a destination follows.
+func (ip *inlineParser) parseCloseBracket(closer token.Token) {
+	opener := ip.brackets
+	if opener == nil {
+		ip.appendText(closer)
+		return
+	}
+	ip.brackets = opener.prev
+
+	s := &scanner{tokens: ip.tokens, index: ip.pos + 1}
+	destination, title, ok := parseInlineLink(s)
+	if !opener.active || !ok || s.offset != 0 {
+		ip.appendText(closer)
+		return
+	}
+
+	ip.processEmphasis(opener.delimiters)
+
+	node := ast.NewNode(ast.LINK)
+	if opener.image {
+		node.Type = ast.IMAGE
+	}
+	node.Destination = destination
+	node.Title = title
+	node.Pos = opener.item.node.Pos
+	node.End = ip.tokens[s.index-1].End()
+	appendItems(node, opener.item.next, nil)
+	ip.tail = opener.item
+	opener.item.next = nil
+	ip.removeItem(opener.item)
+	ip.append(node)
+	ip.pos = s.index - 1
+
+	if !opener.image {
+		for b := ip.brackets; b != nil; b = b.prev {
+			if b.image {
+				continue
+			}
+			if !b.active {
+				// the brackets below were deactivated by an earlier link
+				break
+			}
+			b.active = false
+		}
+	}
+}
+
+// processEmphasis matches the delimiters above stackBottom and turns them into EMPHASIS and STRONG nodes.
+func (ip *inlineParser) processEmphasis(stackBottom *delimiter) {
+	// openersBottom is the lowest delimiter an opener may be found above, for each kind of closer
+	type openersKey struct {
+		ch      byte
+		canOpen bool
+		mod3    int
+	}
+	openersBottom := map[openersKey]*delimiter{}
+
+	closer := ip.delimiters
+	for closer != nil && closer.prev != stackBottom {
+		closer = closer.prev
+	}
+
+	for closer != nil {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+
+		key := openersKey{ch: closer.ch, canOpen: closer.canOpen, mod3: closer.origDelims % 3}
+		bottom, hasBottom := openersBottom[key]
+
+		opener := closer.prev
+		found := false
+		for opener != nil && opener != stackBottom && !(hasBottom && opener == bottom) {
+			// rule of 3
+			oddMatch := (closer.canOpen || opener.canClose) &&
+				closer.origDelims%3 != 0 && (opener.origDelims+closer.origDelims)%3 == 0
+			if opener.ch == closer.ch && opener.canOpen && !oddMatch {
+				found = true
+				break
+			}
+			opener = opener.prev
+		}
+
+		oldCloser := closer
+		if found {
+			use := 1
+			nodeType := ast.NodeType(ast.EMPHASIS)
+			if closer.numDelims >= 2 && opener.numDelims >= 2 {
+				use = 2
+				nodeType = ast.STRONG
+			}
+			opener.numDelims -= use
+			closer.numDelims -= use
+			// the opener is used from its end and the closer from its start
+			openerNode, closerNode := opener.item.node, closer.item.node
+			openerNode.End = openerNode.Pos.Advance(openerNode.Literal[:opener.numDelims])
+			openerNode.Literal = openerNode.Literal[:opener.numDelims]
+			closerNode.Pos = closerNode.Pos.Advance(closerNode.Literal[:use])
+			closerNode.Literal = closerNode.Literal[use:]
+
+			emph := &item{node: ast.NewNode(nodeType)}
+			emph.node.Delimiter = opener.ch
+			emph.node.Pos = openerNode.End
+			emph.node.End = closerNode.Pos
+			appendItems(emph.node, opener.item.next, closer.item)
+			emph.prev = opener.item
+			emph.next = closer.item
+			opener.item.next = emph
+			closer.item.prev = emph
+
+			// the delimiters between the opener and the closer can't match any more
+			opener.next = closer
+			closer.prev = opener
+
+			if opener.numDelims == 0 {
+				ip.removeItem(opener.item)
+				ip.removeDelimiter(opener)
+			}
+			if closer.numDelims == 0 {
+				next := closer.next
+				ip.removeItem(closer.item)
+				ip.removeDelimiter(closer)
+				closer = next
+			}
+		} else {
+			closer = closer.next
+			openersBottom[key] = oldCloser.prev
+			if !oldCloser.canOpen {
+				ip.removeDelimiter(oldCloser)
+			}
+		}
+	}
+
+	for ip.delimiters != nil && ip.delimiters != stackBottom {
+		ip.removeDelimiter(ip.delimiters)
+	}
+}
+
+func (ip *inlineParser) append(node *ast.Node) *item {
+	it := &item{node: node, prev: ip.tail}
+	if ip.tail == nil {
+		ip.head = it
+	} else {
+		ip.tail.next = it
+	}
+	ip.tail = it
+	return it
+}
+
+// appendText adds the literal of tok as text.
+func (ip *inlineParser) appendText(tok token.Token) *item {
+	node := ast.NewNode(ast.TEXT)
+	node.Literal = tok.Literal
+	node.Pos = tok.Pos
+	node.End = tok.End()
+	return ip.append(node)
+}
+
+// appendPlainText adds the literal of tok as text, to the text before it if nothing refers to that,
+// so that a run of words is one node rather than one for each token.
+func (ip *inlineParser) appendPlainText(tok token.Token) {
+	it := ip.tail
+	if it == nil || !it.plain {
+		ip.appendText(tok).plain = true
+		return
+	}
+	if !it.owned {
+		it.node.Literal = append(make([]byte, 0, 2*(len(it.node.Literal)+len(tok.Literal))), it.node.Literal...)
+		it.owned = true
+	}
+	it.node.Literal = append(it.node.Literal, tok.Literal...)
+	it.node.End = tok.End()
+}
+
+func (ip *inlineParser) removeItem(it *item) {
+	if it.prev == nil {
+		ip.head = it.next
+	} else {
+		it.prev.next = it.next
+	}
+	if it.next == nil {
+		ip.tail = it.prev
+	} else {
+		it.next.prev = it.prev
+	}
+}
+
+func (ip *inlineParser) removeDelimiter(d *delimiter) {
+	if d.prev != nil {
+		d.prev.next = d.next
+	}
+	if d.next == nil {
+		ip.delimiters = d.prev
+	} else {
+		d.next.prev = d.prev
+	}
+}
+
+// appendItems adds the nodes of the items from first up to last (exclusive) to parent.
+// Adjacent TEXT nodes are merged into one, copying each literal only once.
+func appendItems(parent *ast.Node, first, last *item) {
+	for it := first; it != last; {
+		if it.node.Type != ast.TEXT {
+			parent.AppendChild(it.node)
+			it = it.next
+			continue
+		}
+
+		end, size := it, 0
+		for ; end != last && end.node.Type == ast.TEXT; end = end.next {
+			size += len(end.node.Literal)
+		}
+		if size == 0 {
+			it = end
+			continue
+		}
+		if it.next == end {
+			parent.AppendChild(it.node)
+			it = end
+			continue
+		}
+
+		// Literal may share its array with the input, so always copy
+		literal := make([]byte, 0, size)
+		node := ast.NewNode(ast.TEXT)
+		node.Pos = it.node.Pos
+		for ; it != end; it = it.next {
+			literal = append(literal, it.node.Literal...)
+			node.End = it.node.End
+		}
+		node.Literal = literal
+		parent.AppendChild(node)
+	}
+}
@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"bytes"
+
+	"github.com/istsh/markdown-viewer/token"
+)
+
+const tabStop = 4
+
+// line is a single line of tokens without its trailing LINE_FEED_CODE.
+// It keeps a cursor so that container markers and indentation can be consumed column by column.
+type line struct {
+	tokens []token.Token
+
+	index  int // index of the token at the cursor
+	offset int // byte offset into the literal of tokens[index]
+	column int // column of the cursor, with tab stops of 4
+
+	// partial is the number of columns left over from a tab that was only partly consumed.
+	partial int
+
+	// end is the position of the end of the line, before its line ending.
+	end token.Position
+}
+
+func newLine(tokens []token.Token, end token.Position) *line {
+	return &line{tokens: tokens, end: end}
+}
+
+// pos returns the position of the cursor in the source.
+// Within a partly consumed tab, it is the position after the tab.
+func (ln *line) pos() token.Position {
+	if ln.index >= len(ln.tokens) {
+		return ln.end
+	}
+	tok := ln.tokens[ln.index]
+	return tok.Pos.Advance(tok.Literal[:ln.offset])
+}
+
+// ch returns the byte at the cursor, or 0 at the end of the line.
+func (ln *line) ch() byte {
+	if ln.partial > 0 {
+		return ' '
+	}
+	if ln.index >= len(ln.tokens) {
+		return 0
+	}
+	return ln.tokens[ln.index].Literal[ln.offset]
+}
+
+// next moves the cursor by one byte.
+func (ln *line) next() {
+	if ln.partial > 0 {
+		ln.column += ln.partial
+		ln.partial = 0
+		return
+	}
+	if ln.index >= len(ln.tokens) {
+		return
+	}
+
+	if ln.tokens[ln.index].Literal[ln.offset] == '\t' {
+		ln.column += tabStop - ln.column%tabStop
+	} else {
+		ln.column++
+	}
+
+	ln.offset++
+	if ln.offset >= len(ln.tokens[ln.index].Literal) {
+		ln.index++
+		ln.offset = 0
+	}
+}
+
+// token returns the token at the cursor if the cursor is on a token boundary.
+func (ln *line) token() (token.Token, bool) {
+	if ln.partial > 0 || ln.offset > 0 || ln.index >= len(ln.tokens) {
+		return token.Token{}, false
+	}
+	return ln.tokens[ln.index], true
+}
+
+// advance consumes the token at the cursor.
+func (ln *line) advance() {
+	index := ln.index
+	for ln.index == index && ln.index < len(ln.tokens) {
+		ln.next()
+	}
+}
+
+// indent returns the width of the whitespace at the cursor without consuming it.
+func (ln *line) indent() int {
+	saved := *ln
+	ln.skipIndent()
+	width := ln.column - saved.column
+	*ln = saved
+	return width
+}
+
+// skipIndent consumes all whitespace at the cursor.
+func (ln *line) skipIndent() {
+	for isWhitespace(ln.ch()) {
+		ln.next()
+	}
+}
+
+// skipColumns consumes at most n columns of whitespace at the cursor.
+// A tab that is wider than the remaining columns is consumed partly.
+func (ln *line) skipColumns(n int) {
+	for n > 0 {
+		if ln.partial > 0 {
+			c := ln.partial
+			if c > n {
+				c = n
+			}
+			ln.partial -= c
+			ln.column += c
+			n -= c
+			continue
+		}
+
+		switch ln.ch() {
+		case ' ':
+			ln.next()
+			n--
+		case '\t':
+			width := tabStop - ln.column%tabStop
+			column := ln.column
+			ln.next()
+			if width > n {
+				ln.column = column + n
+				ln.partial = width - n
+				n = 0
+			} else {
+				n -= width
+			}
+		default:
+			return
+		}
+	}
+}
+
+// blank reports whether the rest of the line is whitespace only.
+func (ln *line) blank() bool {
+	saved := *ln
+	ln.skipIndent()
+	blank := ln.ch() == 0
+	*ln = saved
+	return blank
+}
+
+// rest returns the tokens after the cursor.
+// The part of a token that was partly consumed is returned as a token of its own.
+func (ln *line) rest() []token.Token {
+	return ln.appendRest(nil)
+}
+
+// appendRest appends the tokens after the cursor to tokens, like rest.
+func (ln *line) appendRest(tokens []token.Token) []token.Token {
+	if ln.partial > 0 {
+		tokens = append(tokens, token.Token{Type: token.SPACE, Literal: bytes.Repeat([]byte{' '}, ln.partial), Pos: ln.pos()})
+	}
+	if ln.index >= len(ln.tokens) {
+		return tokens
+	}
+
+	if ln.offset > 0 {
+		tok := ln.tokens[ln.index]
+		literal := tok.Literal[ln.offset:]
+		tokenType := tok.Type
+		if isWhitespaceOnly(literal) {
+			tokenType = token.GetTabToken(len(literal))
+		}
+		tokens = append(tokens, token.Token{Type: tokenType, Literal: literal, Pos: ln.pos()})
+		return append(tokens, ln.tokens[ln.index+1:]...)
+	}
+	return append(tokens, ln.tokens[ln.index:]...)
+}
+
+// restBytes returns the source text after the cursor.
+func (ln *line) restBytes() []byte {
+	var chs []byte
+	for _, tok := range ln.rest() {
+		chs = append(chs, tok.Literal...)
+	}
+	return chs
+}
+
+func isWhitespace(ch byte) bool {
+	return ch == ' ' || ch == '\t'
+}
+
+func isWhitespaceOnly(chs []byte) bool {
+	for _, ch := range chs {
+		if !isWhitespace(ch) {
+			return false
+		}
+	}
+	return true
+}
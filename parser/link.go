@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/token"
+)
+
+// maxLinkNesting is the deepest nesting of parentheses allowed in a link destination.
+// It also keeps a destination that is never closed from being scanned to the end of the block by every bracket.
+const maxLinkNesting = 32
+
+// scanner reads the literals of a sequence of tokens byte by byte.
+type scanner struct {
+	tokens []token.Token
+	index  int
+	offset int
+}
+
+// peek returns the byte at the scanner, or 0 at the end of the tokens.
+func (s *scanner) peek() byte {
+	if s.index >= len(s.tokens) {
+		return 0
+	}
+	return s.tokens[s.index].Literal[s.offset]
+}
+
+func (s *scanner) next() {
+	if s.index >= len(s.tokens) {
+		return
+	}
+	s.offset++
+	if s.offset >= len(s.tokens[s.index].Literal) {
+		s.index++
+		s.offset = 0
+	}
+}
+
+// skipSpaces consumes spaces, tabs and at most one line ending.
+func (s *scanner) skipSpaces() {
+	lineEnding := false
+	for {
+		switch ch := s.peek(); {
+		case isWhitespace(ch):
+		case ch == '\n' && !lineEnding:
+			lineEnding = true
+		default:
+			return
+		}
+		s.next()
+	}
+}
+
+// parseInlineLink parses `(destination "title")` from the start of s.
+func parseInlineLink(s *scanner) (destination, title []byte, ok bool) {
+	if s.peek() != '(' {
+		return nil, nil, false
+	}
+	s.next()
+	s.skipSpaces()
+
+	if s.peek() != ')' {
+		destination, ok = parseLinkDestination(s)
+		if !ok {
+			return nil, nil, false
+		}
+
+		// a title must be separated from the destination by whitespace
+		index, offset := s.index, s.offset
+		s.skipSpaces()
+		if s.index != index || s.offset != offset {
+			if t, ok := parseLinkTitle(s); ok {
+				title = t
+				s.skipSpaces()
+			}
+		}
+	}
+
+	if s.peek() != ')' {
+		return nil, nil, false
+	}
+	s.next()
+	return destination, title, true
+}
+
+// parseLinkDestination parses either `<...>` or a run of non-space characters with balanced parentheses.
+func parseLinkDestination(s *scanner) ([]byte, bool) {
+	var destination []byte
+
+	if s.peek() == '<' {
+		s.next()
+		for {
+			ch := s.peek()
+			switch {
+			case ch == '>':
+				s.next()
+				return destination, true
+			case ch == 0, ch == '\n', ch == '<':
+				return nil, false
+			case ch == '\\':
+				s.next()
+				destination = append(destination, unescape(s)...)
+				continue
+			}
+			destination = append(destination, ch)
+			s.next()
+		}
+	}
+
+	depth := 0
+	for {
+		ch := s.peek()
+		switch {
+		case ch == '\\':
+			s.next()
+			destination = append(destination, unescape(s)...)
+			continue
+		case ch == '(':
+			depth++
+			if depth > maxLinkNesting {
+				return nil, false
+			}
+		case ch == ')':
+			if depth == 0 {
+				return destination, len(destination) > 0
+			}
+			depth--
+		case ch <= ' ' || ch == 0x7f:
+			// the destination ends at a space or a control character
+			return destination, depth == 0 && len(destination) > 0
+		}
+		destination = append(destination, ch)
+		s.next()
+	}
+}
+
+// parseLinkTitle parses a title enclosed in `"`, `'` or `()`.
+func parseLinkTitle(s *scanner) ([]byte, bool) {
+	var closer byte
+	switch s.peek() {
+	case '"', '\'':
+		closer = s.peek()
+	case '(':
+		closer = ')'
+	default:
+		return nil, false
+	}
+	opener := s.peek()
+	saved := *s
+	s.next()
+
+	var title []byte
+	for {
+		ch := s.peek()
+		switch {
+		case ch == closer:
+			s.next()
+			return title, true
+		case ch == 0, opener == '(' && ch == '(':
+			*s = saved
+			return nil, false
+		case ch == '\\':
+			s.next()
+			title = append(title, unescape(s)...)
+			continue
+		}
+		title = append(title, ch)
+		s.next()
+	}
+}
+
+// unescape reads the character after a backslash.
+// A backslash before ASCII punctuation is removed, otherwise it is kept.
+func unescape(s *scanner) []byte {
+	ch := s.peek()
+	if ch < 0x80 && ch != 0 && lexer.IsPunctuation(rune(ch)) {
+		s.next()
+		return []byte{ch}
+	}
+	return []byte{'\\'}
+}
@@ -1,9 +1,13 @@
 package parser
 
 import (
-	"fmt"
+	"bytes"
+	"io"
 
+	"github.com/istsh/markdown-viewer/ast"
 	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/html"
 	"github.com/istsh/markdown-viewer/token"
 )
 
@@ -11,6 +15,19 @@ import (
 type Parser struct {
 	l *lexer.Lexer
 	//errors []string
+
+	// open is the chain of blocks that are still open, starting from the document.
+	open []*block
+
+	// lineNumber is the number of the line being processed, counted from 1.
+	lineNumber int
+	// lineTokens holds the tokens of the line being processed, reused for each line.
+	// The blocks copy the tokens they keep.
+	lineTokens []token.Token
+
+	// emit receives each top-level block as soon as it is closed.
+	emit func(*ast.Node) error
+	err  error
 }
 
 // New initializes Parser.
@@ -23,103 +40,82 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
-// Parse parses markdown text to html text.
-func (p *Parser) Parse() []byte {
-	var result []byte
+// ParseDocument parses markdown text to a document tree.
+func (p *Parser) ParseDocument() *ast.Node {
+	doc := ast.NewNode(ast.DOCUMENT)
+	p.parse(doc)
+	doc.LineEnding = p.l.LineEnding()
 
-	var pendingToken token.TokenType
-	for {
-		currentToken := p.l.NextToken()
-		if currentToken.Type == token.EOF {
+	return doc
+}
+
+// ParseBlocks parses markdown text and calls fn for each top-level block as soon as it is closed.
+// The blocks are not kept, so the memory used grows with the longest block rather than with the length of the document.
+func (p *Parser) ParseBlocks(fn func(block *ast.Node) error) error {
+	p.emit = fn
+	defer func() {
+		p.emit = nil
+	}()
+
+	p.parse(ast.NewNode(ast.DOCUMENT))
+	if p.err != nil {
+		return p.err
+	}
+	return p.l.Err()
+}
+
+// ParseTo parses markdown text and writes html text to w block by block.
+func (p *Parser) ParseTo(w io.Writer) error {
+	return p.RenderTo(w, html.NewRenderer())
+}
+
+// RenderTo parses markdown text and writes it to w with r block by block.
+func (p *Parser) RenderTo(w io.Writer, r renderer.Renderer) error {
+	return p.ParseBlocks(func(block *ast.Node) error {
+		return renderer.Render(w, r, block)
+	})
+}
+
+func (p *Parser) parse(doc *ast.Node) {
+	doc.Pos = token.Position{Line: 1, Column: 1}
+	p.open = []*block{{node: doc}}
+	p.lineNumber = 0
+
+	for p.err == nil {
+		tokens, end, ok := p.readLine()
+		if !ok {
+			doc.End = end
 			break
 		}
+		p.lineNumber++
+		p.processLine(newLine(tokens, end))
+	}
+	// the blocks still open end at the last line
+	p.lineNumber++
+	p.closeBlocks(0)
+}
 
-		switch currentToken.Type {
-		case token.HEADING1:
-			pendingToken = token.HEADING1
-			result = appendStr(result, "<h1>")
-		case token.HEADING2:
-			pendingToken = token.HEADING2
-			result = appendStr(result, "<h2>")
-		case token.HEADING3:
-			pendingToken = token.HEADING3
-			result = appendStr(result, "<h3>")
-		case token.HEADING4:
-			pendingToken = token.HEADING4
-			result = appendStr(result, "<h4>")
-		case token.HEADING5:
-			pendingToken = token.HEADING5
-			result = appendStr(result, "<h5>")
-		case token.HEADING6:
-			pendingToken = token.HEADING6
-			result = appendStr(result, "<h6>")
-		case token.BACK_QUOTE_BEGIN:
-			result = appendStr(result, "<back_quote>")
-		case token.BACK_QUOTE_FINISH:
-			result = appendStr(result, "</back_quote>")
-		case token.ASTERISK_ITALIC_BEGIN, token.UNDER_SCORE_ITALIC_BEGIN:
-			result = appendStr(result, "<italic>")
-		case token.ASTERISK_ITALIC_FINISH, token.UNDER_SCORE_ITALIC_FINISH:
-			result = appendStr(result, "</italic>")
-		case token.ASTERISK_BOLD_BEGIN, token.UNDER_SCORE_BOLD_BEGIN:
-			result = appendStr(result, "<bold>")
-		case token.ASTERISK_BOLD_FINISH, token.UNDER_SCORE_BOLD_FINISH:
-			result = appendStr(result, "</bold>")
-		case token.ASTERISK_ITALIC_BOLD_BEGIN, token.UNDER_SCORE_ITALIC_BOLD_BEGIN:
-			result = appendStr(result, "<italic_bold>")
-		case token.ASTERISK_ITALIC_BOLD_FINISH, token.UNDER_SCORE_ITALIC_BOLD_FINISH:
-			result = appendStr(result, "</italic_bold>")
-		case token.CITATION1:
-			result = appendStr(result, "<citation1>")
-		case token.CITATION2:
-			result = appendStr(result, "<citation2>")
-		case token.SPACE:
-			result = appendStr(result, "<space>")
-		case token.HYPHEN:
-			result = appendStr(result, "<hyphen>")
-		case token.STRING:
-			result = append(result, currentToken.Literal...)
-		case token.TAB1:
-			result = appendStr(result, "\t")
-		case token.TAB2:
-			result = appendStr(result, "\t\t")
-		case token.TAB3:
-			result = appendStr(result, "\t\t\t")
-		case token.HORIZON:
-			result = appendStr(result, "<hr>")
-		case token.LINK_TEXT_BEGIN:
-			result = appendStr(result, "<a link=\"\">")
-		case token.LINK_TEXT_FINISH:
-			result = appendStr(result, "</a>")
-		case token.LINK_BEGIN:
-			result = append(result, currentToken.Literal...)
-		case token.LINK_FINISH:
-			result = append(result, currentToken.Literal...)
+// readLine reads the tokens up to the next LINE_FEED_CODE, and returns them with the position of the end of the line.
+// The tokens are valid until the next call.
+func (p *Parser) readLine() ([]token.Token, token.Position, bool) {
+	p.lineTokens = p.lineTokens[:0]
+	for {
+		tok := p.l.NextToken()
+		switch tok.Type {
+		case token.EOF:
+			return p.lineTokens, tok.Pos, len(p.lineTokens) > 0
 		case token.LINE_FEED_CODE:
-			switch pendingToken {
-			case token.HEADING1:
-				result = appendStr(result, "</h1>")
-			case token.HEADING2:
-				result = appendStr(result, "</h2>")
-			case token.HEADING3:
-				result = appendStr(result, "</h3>")
-			case token.HEADING4:
-				result = appendStr(result, "</h4>")
-			case token.HEADING5:
-				result = appendStr(result, "</h5>")
-			case token.HEADING6:
-				result = appendStr(result, "</h6>")
-			}
-			pendingToken = token.NONE
-			result = appendStr(result, "\n")
-		default:
-			panic(fmt.Sprintf("unsupported token type: %q", currentToken.Type))
+			return p.lineTokens, tok.Pos, true
 		}
+		p.lineTokens = append(p.lineTokens, tok)
 	}
-
-	return result
 }
 
-func appendStr(slice []byte, str string) []byte {
-	return append(slice, []byte(str)...)
+// Parse parses markdown text to html text.
+func (p *Parser) Parse() []byte {
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, html.NewRenderer(), p.ParseDocument()); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
 }
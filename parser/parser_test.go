@@ -1 +1,395 @@
 package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/lexer"
+)
+
+type expected struct {
+	input string
+	want  string
+}
+
+func compareGotAndWant(t *testing.T, tests []expected) {
+	for i, tt := range tests {
+		p := New(lexer.New([]byte(tt.input)))
+		got := string(p.Parse())
+		if got != tt.want {
+			t.Errorf("tests[%d] - html wrong.\ninput=%q\nexpected=%q\ngot=%q", i, tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestParseBlockQuote(t *testing.T) {
+	tests := []expected{
+		{
+			input: "> Description1\n> Description2\n",
+			want:  "<blockquote>\n<p>Description1\nDescription2</p>\n</blockquote>\n",
+		},
+		{
+			input: ">>> Description1\n",
+			want:  "<blockquote>\n<blockquote>\n<blockquote>\n<p>Description1</p>\n</blockquote>\n</blockquote>\n</blockquote>\n",
+		},
+		{
+			input: "> Description1\n>> Description2\n> > Description3\n",
+			want:  "<blockquote>\n<p>Description1</p>\n<blockquote>\n<p>Description2\nDescription3</p>\n</blockquote>\n</blockquote>\n",
+		},
+		{
+			// lazy continuation line
+			input: "> > Description1\nDescription2\n",
+			want:  "<blockquote>\n<blockquote>\n<p>Description1\nDescription2</p>\n</blockquote>\n</blockquote>\n",
+		},
+		{
+			input: "> Description1\n\n> Description2\n",
+			want:  "<blockquote>\n<p>Description1</p>\n</blockquote>\n<blockquote>\n<p>Description2</p>\n</blockquote>\n",
+		},
+		{
+			input: "> # Heading1\n> - List1\n> - List2\n",
+			want:  "<blockquote>\n<h1>Heading1</h1>\n<ul>\n<li>List1</li>\n<li>List2</li>\n</ul>\n</blockquote>\n",
+		},
+		{
+			input: "> ```go\n> a := 1\n> ```\n",
+			want:  "<blockquote>\n<pre><code class=\"language-go\">a := 1\n</code></pre>\n</blockquote>\n",
+		},
+		{
+			input: ">     a := 1\n",
+			want:  "<blockquote>\n<pre><code>a := 1\n</code></pre>\n</blockquote>\n",
+		},
+		{
+			// a fenced code block is not continued lazily
+			input: "> ```\n> a\nb\n",
+			want:  "<blockquote>\n<pre><code>a\n</code></pre>\n</blockquote>\n<p>b</p>\n",
+		},
+		{
+			input: "Description1 > Description2\n",
+			want:  "<p>Description1 &gt; Description2</p>\n",
+		},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestParseBlocks(t *testing.T) {
+	tests := []expected{
+		{
+			input: "# Heading1\nDescription1\nDescription2\n\nDescription3\n",
+			want:  "<h1>Heading1</h1>\n<p>Description1\nDescription2</p>\n<p>Description3</p>\n",
+		},
+		{
+			input: "- List1\n\t- List1_1\n\t\t- List1_1_1\n- List2\n",
+			want:  "<ul>\n<li>List1\n<ul>\n<li>List1_1\n<ul>\n<li>List1_1_1</li>\n</ul>\n</li>\n</ul>\n</li>\n<li>List2</li>\n</ul>\n",
+		},
+		{
+			input: "```\n# not heading\n```\n---\n",
+			want:  "<pre><code># not heading\n</code></pre>\n<hr>\n",
+		},
+		{
+			input: "    a := 1\n\n    b := 2\n\n",
+			want:  "<pre><code>a := 1\n\nb := 2\n</code></pre>\n",
+		},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestParseList(t *testing.T) {
+	tests := []expected{
+		{input: "- List1\n  - List1_1\n    - List1_1_1\n      - List1_1_1_1\n", want: "<ul>\n<li>List1\n<ul>\n<li>List1_1\n<ul>\n<li>List1_1_1\n<ul>\n<li>List1_1_1_1</li>\n</ul>\n</li>\n</ul>\n</li>\n</ul>\n</li>\n</ul>\n"},
+		{input: "- List1\n\t- List1_1\n\t\t- List1_1_1\n\t\t\t- List1_1_1_1\n", want: "<ul>\n<li>List1\n<ul>\n<li>List1_1\n<ul>\n<li>List1_1_1\n<ul>\n<li>List1_1_1_1</li>\n</ul>\n</li>\n</ul>\n</li>\n</ul>\n</li>\n</ul>\n"},
+		{input: "- a\n - b\n  - c\n   - d\n    - e\n", want: "<ul>\n<li>a</li>\n<li>b</li>\n<li>c</li>\n<li>d\n- e</li>\n</ul>\n"},
+		{input: "- List1\n\n  Description1\n- List2\n", want: "<ul>\n<li>\n<p>List1</p>\n<p>Description1</p>\n</li>\n<li>\n<p>List2</p>\n</li>\n</ul>\n"},
+		{input: "- List1\n\n- List2\n", want: "<ul>\n<li>\n<p>List1</p>\n</li>\n<li>\n<p>List2</p>\n</li>\n</ul>\n"},
+		{input: "- List1\n  - List1_1\n\n    Description1\n- List2\n", want: "<ul>\n<li>List1\n<ul>\n<li>\n<p>List1_1</p>\n<p>Description1</p>\n</li>\n</ul>\n</li>\n<li>List2</li>\n</ul>\n"},
+		{input: "- List1\n  ```go\n  code\n  ```\n- List2\n  > Description1\n", want: "<ul>\n<li>List1\n<pre><code class=\"language-go\">code\n</code></pre>\n</li>\n<li>List2\n<blockquote>\n<p>Description1</p>\n</blockquote>\n</li>\n</ul>\n"},
+		{input: "-   List1\n\n        code\n", want: "<ul>\n<li>\n<p>List1</p>\n<pre><code>code\n</code></pre>\n</li>\n</ul>\n"},
+		{input: "-\n  List1\n-\n\n  Description1\n", want: "<ul>\n<li>List1</li>\n<li></li>\n</ul>\n<p>Description1</p>\n"},
+		{input: "Description1\n-\n", want: "<p>Description1\n-</p>\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestParseOrderedList(t *testing.T) {
+	tests := []expected{
+		{input: "1. List1\n2. List2\n", want: "<ol>\n<li>List1</li>\n<li>List2</li>\n</ol>\n"},
+		{input: "3. List1\n4. List2\n", want: "<ol start=\"3\">\n<li>List1</li>\n<li>List2</li>\n</ol>\n"},
+		{input: "1. List1\n2. List2\n3) List3\n", want: "<ol>\n<li>List1</li>\n<li>List2</li>\n</ol>\n<ol start=\"3\">\n<li>List3</li>\n</ol>\n"},
+		{input: "- List1\n1. List2\n", want: "<ul>\n<li>List1</li>\n</ul>\n<ol>\n<li>List2</li>\n</ol>\n"},
+		{input: "1. List1\n\n2. List2\n", want: "<ol>\n<li>\n<p>List1</p>\n</li>\n<li>\n<p>List2</p>\n</li>\n</ol>\n"},
+		{input: "10. List1\n    - List1_1\n", want: "<ol start=\"10\">\n<li>List1\n<ul>\n<li>List1_1</li>\n</ul>\n</li>\n</ol>\n"},
+		{input: "Description1\n2. Description2\n1. List1\n", want: "<p>Description1\n2. Description2</p>\n<ol>\n<li>List1</li>\n</ol>\n"},
+		{input: "1234567890. Description1\n", want: "<p>1234567890. Description1</p>\n"},
+		{input: "1.Description1\n", want: "<p>1.Description1</p>\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestParseEmphasis(t *testing.T) {
+	tests := []expected{
+		{input: "*italic area*\n", want: "<p><em>italic area</em></p>\n"},
+		{input: "**bold area**\n", want: "<p><strong>bold area</strong></p>\n"},
+		{input: "___italic & bold area___\n", want: "<p><em><strong>italic &amp; bold area</strong></em></p>\n"},
+		{input: "*a*.\n", want: "<p><em>a</em>.</p>\n"},
+		{input: "foo*bar*baz\n", want: "<p>foo<em>bar</em>baz</p>\n"},
+		{input: "foo_bar_baz\n", want: "<p>foo_bar_baz</p>\n"},
+		{input: "_foo_bar_\n", want: "<p><em>foo_bar</em></p>\n"},
+		{input: "snake__case_x\n", want: "<p>snake__case_x</p>\n"},
+		{input: "***a** b*\n", want: "<p><em><strong>a</strong> b</em></p>\n"},
+		{input: "*foo**bar**baz*\n", want: "<p><em>foo<strong>bar</strong>baz</em></p>\n"},
+		{input: "**foo*\n", want: "<p>*<em>foo</em></p>\n"},
+		{input: "*Description1\nDescription2*\n", want: "<p><em>Description1\nDescription2</em></p>\n"},
+		{input: "a * b * c\n", want: "<p>a * b * c</p>\n"},
+		{input: "*(*foo*)*\n", want: "<p><em>(<em>foo</em>)</em></p>\n"},
+		{input: "日本語*強調*です\n", want: "<p>日本語<em>強調</em>です</p>\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestParseCodeSpan(t *testing.T) {
+	tests := []expected{
+		{input: "`back quote area`\n", want: "<p><code>back quote area</code></p>\n"},
+		{input: "`` a`b ``\n", want: "<p><code>a`b</code></p>\n"},
+		{input: "```foo``` and `` ` ``\n", want: "<p><code>foo</code> and <code>`</code></p>\n"},
+		{input: "`x`, `y`.\n", want: "<p><code>x</code>, <code>y</code>.</p>\n"},
+		{input: "`*not italic* <b>`\n", want: "<p><code>*not italic* &lt;b&gt;</code></p>\n"},
+		{input: "*foo`*`\n", want: "<p>*foo<code>*</code></p>\n"},
+		{input: "`Description1\nDescription2`\n", want: "<p><code>Description1 Description2</code></p>\n"},
+		{input: "`  `\n", want: "<p><code>  </code></p>\n"},
+		{input: "``unclosed`\n", want: "<p>``unclosed`</p>\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestParseLink(t *testing.T) {
+	tests := []expected{
+		{input: "[Google](https://www.google.com/)\n", want: "<p><a href=\"https://www.google.com/\">Google</a></p>\n"},
+		{input: "[Google](https://www.google.com/\n", want: "<p>[Google](https://www.google.com/</p>\n"},
+		{input: "[docs](docs/index.md)\n", want: "<p><a href=\"docs/index.md\">docs</a></p>\n"},
+		{input: "[mail](mailto:foo@example.com)\n", want: "<p><a href=\"mailto:foo@example.com\">mail</a></p>\n"},
+		{input: "[anchor](#section)\n", want: "<p><a href=\"#section\">anchor</a></p>\n"},
+		{input: "[wiki](https://example.com/Foo_(bar))\n", want: "<p><a href=\"https://example.com/Foo_(bar)\">wiki</a></p>\n"},
+		{input: "[home](https://example.com/~foo#bar)\n", want: "<p><a href=\"https://example.com/~foo#bar\">home</a></p>\n"},
+		{input: "[a](<b c>)\n", want: "<p><a href=\"b c\">a</a></p>\n"},
+		{input: "[a](b\\)c)\n", want: "<p><a href=\"b)c\">a</a></p>\n"},
+		{input: "[a](b \"Title\")\n", want: "<p><a href=\"b\" title=\"Title\">a</a></p>\n"},
+		{input: "[a](b 'Title') [c](d (Title))\n", want: "<p><a href=\"b\" title=\"Title\">a</a> <a href=\"d\" title=\"Title\">c</a></p>\n"},
+		{input: "[a [nested] *text*](b)\n", want: "<p><a href=\"b\">a [nested] <em>text</em></a></p>\n"},
+		{input: "[a [b](c) d](e)\n", want: "<p>[a <a href=\"c\">b</a> d](e)</p>\n"},
+		{input: "[a] (b)\n", want: "<p>[a] (b)</p>\n"},
+		{input: "[a](" + strings.Repeat("(", 33) + strings.Repeat(")", 33) + ")\n", want: "<p>[a](" + strings.Repeat("(", 33) + strings.Repeat(")", 33) + ")</p>\n"},
+		{input: "![Logo *image*](logo.png \"Logo\")\n", want: "<p><img src=\"logo.png\" alt=\"Logo image\" title=\"Logo\"></p>\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestParseTable(t *testing.T) {
+	tests := []expected{
+		{
+			input: "| a | b |\n| :-- | --: |\n| 1 | 2 |\n",
+			want:  "<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th align=\"right\">b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td align=\"right\">2</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		// the outer pipes are optional, and rows are filled or cut to the number of columns of the header
+		{
+			input: "a | b\n:-:|-\n1\n1 | 2 | 3\n",
+			want: "<table>\n<thead>\n<tr>\n<th align=\"center\">a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n" +
+				"<tr>\n<td align=\"center\">1</td>\n<td></td>\n</tr>\n<tr>\n<td align=\"center\">1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		// an escaped pipe is a part of the cell, even in a code span
+		{
+			input: "| `a\\|b` | *c* |\n|---|---|\n",
+			want:  "<table>\n<thead>\n<tr>\n<th><code>a|b</code></th>\n<th><em>c</em></th>\n</tr>\n</thead>\n</table>\n",
+		},
+		// the table ends at a blank line or at the start of another block
+		{
+			input: "|a|\n|-|\n|b|\n\nc\n",
+			want:  "<table>\n<thead>\n<tr>\n<th>a</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>b</td>\n</tr>\n</tbody>\n</table>\n<p>c</p>\n",
+		},
+		{
+			input: "|a|\n|-|\n> b\n",
+			want:  "<table>\n<thead>\n<tr>\n<th>a</th>\n</tr>\n</thead>\n</table>\n<blockquote>\n<p>b</p>\n</blockquote>\n",
+		},
+		// the numbers of cells of the header and the delimiter row must match
+		{
+			input: "| a | b |\n| --- |\n",
+			want:  "<p>| a | b |\n| --- |</p>\n",
+		},
+		// only the first line of a paragraph can be the header
+		{
+			input: "a\n| b |\n| --- |\n",
+			want:  "<p>a\n| b |\n| --- |</p>\n",
+		},
+		{
+			input: "> | a |\n> | - |\n",
+			want:  "<blockquote>\n<table>\n<thead>\n<tr>\n<th>a</th>\n</tr>\n</thead>\n</table>\n</blockquote>\n",
+		},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestParseLineEnding(t *testing.T) {
+	tests := []expected{
+		{input: "# Heading1\r\n\r\nDescription1\r\nDescription2\r\n", want: "<h1>Heading1</h1>\n<p>Description1\nDescription2</p>\n"},
+		{input: "- List1\r\n- List2\r\n", want: "<ul>\n<li>List1</li>\n<li>List2</li>\n</ul>\n"},
+		{input: "> Description1\rDescription2\r\r```\rcode\r```\r", want: "<blockquote>\n<p>Description1\nDescription2</p>\n</blockquote>\n<pre><code>code\n</code></pre>\n"},
+		{input: "`a\r\nb`\r\n", want: "<p><code>a b</code></p>\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestParseTo(t *testing.T) {
+	input := "# Heading1\n> Description1\n> - List1\n\n```\ncode\n```\nDescription2\n"
+
+	want := New(lexer.New([]byte(input))).Parse()
+	var got bytes.Buffer
+	if err := New(lexer.NewReader(strings.NewReader(input))).ParseTo(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("html wrong.\nexpected=%q\ngot=%q", want, got.Bytes())
+	}
+}
+
+func TestParseBlocksStreaming(t *testing.T) {
+	input := "# Heading1\n> Description1\n> - List1\n\n```\ncode\n```\nDescription2\n"
+	want := []ast.NodeType{ast.HEADING, ast.BLOCK_QUOTE, ast.CODE_BLOCK, ast.PARAGRAPH}
+
+	var got []ast.NodeType
+	err := New(lexer.NewReader(strings.NewReader(input))).ParseBlocks(func(block *ast.Node) error {
+		if len(block.Parent.Children) != 1 {
+			t.Errorf("blocks already emitted are kept: %d", len(block.Parent.Children))
+		}
+		got = append(got, block.Type)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("blocks wrong. expected=%v, got=%v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("blocks[%d] wrong. expected=%s, got=%s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{
+			input: "# Heading *1*\n",
+			want:  []string{"DOCUMENT \"# Heading *1*\\n\"", "HEADING \"# Heading *1*\"", "TEXT \"Heading \"", "EMPHASIS \"*1*\"", "TEXT \"1\""},
+		},
+		{
+			input: "> ***a**b*\nc",
+			want: []string{"DOCUMENT \"> ***a**b*\\nc\"", "BLOCK_QUOTE \"> ***a**b*\\nc\"", "PARAGRAPH \"***a**b*\\nc\"",
+				"EMPHASIS \"***a**b*\"", "STRONG \"**a**\"", "TEXT \"a\"", "TEXT \"b\"", "SOFT_BREAK \"\\n\"", "TEXT \"c\""},
+		},
+		{
+			input: "- `a` [b](c)\n\n      code\n\n",
+			want: []string{"DOCUMENT \"- `a` [b](c)\\n\\n      code\\n\\n\"", "LIST \"- `a` [b](c)\\n\\n      code\"", "LIST_ITEM \"- `a` [b](c)\\n\\n      code\"",
+				"PARAGRAPH \"`a` [b](c)\"", "CODE \"`a`\"", "TEXT \" \"", "LINK \"[b](c)\"", "TEXT \"b\"", "CODE_BLOCK \"    code\""},
+		},
+	}
+
+	for i, tt := range tests {
+		doc := New(lexer.New([]byte(tt.input))).ParseDocument()
+
+		var got []string
+		var walk func(node *ast.Node)
+		walk = func(node *ast.Node) {
+			got = append(got, fmt.Sprintf("%s %q", node.Type, tt.input[node.Pos.Offset:node.End.Offset]))
+			for _, child := range node.Children {
+				walk(child)
+			}
+		}
+		walk(doc)
+
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("tests[%d] - positions wrong.\nexpected=%s\ngot=%s", i, strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	units := []struct {
+		name string
+		unit string
+	}{
+		{name: "Text", unit: "word "},
+		{name: "Emphasis", unit: "*a* __b__ "},
+		{name: "CodeSpan", unit: "`a` ``"},
+		{name: "Link", unit: "[a](b) "},
+		{name: "Bracket", unit: "[a](b"},
+		{name: "Lines", unit: "a *b* [c](d) `e`\nf g h\n\n"},
+	}
+
+	for _, u := range units {
+		for _, size := range []int{1 << 16, 1 << 18, 1 << 20} {
+			input := []byte(strings.Repeat(u.unit, size/len(u.unit)))
+			b.Run(fmt.Sprintf("%s/%dKB", u.name, size>>10), func(b *testing.B) {
+				b.SetBytes(int64(len(input)))
+				for i := 0; i < b.N; i++ {
+					New(lexer.New(input)).Parse()
+				}
+			})
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	goldenPaths, err := filepath.Glob("../testdata/*.md.golden")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, goldenPath := range goldenPaths {
+		input, err := ioutil.ReadFile(goldenPath)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(input)
+	}
+	// inputs that used to read past the end of the input forever
+	f.Add([]byte("-"))
+	f.Add([]byte("Description1\n#"))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		want := New(lexer.New(input)).Parse()
+
+		var got bytes.Buffer
+		if err := New(lexer.New(input)).ParseTo(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("html of ParseTo wrong.\nexpected=%q\ngot=%q", want, got.Bytes())
+		}
+
+		var checkPosition func(node *ast.Node)
+		checkPosition = func(node *ast.Node) {
+			if node.Pos.Offset < 0 || node.Pos.Offset > node.End.Offset || node.End.Offset > len(input) {
+				t.Errorf("position of %s out of range: %d-%d", node.Type, node.Pos.Offset, node.End.Offset)
+			}
+			for _, child := range node.Children {
+				checkPosition(child)
+			}
+		}
+		checkPosition(New(lexer.New(input)).ParseDocument())
+	})
+}
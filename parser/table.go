@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"bytes"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/token"
+)
+
+// tableRow is a line of a TABLE: its tokens without the indentation, and the end of the line.
+type tableRow struct {
+	tokens []token.Token
+	end    token.Position
+}
+
+// tableCell is the content of a cell between two pipes, and where it starts.
+type tableCell struct {
+	tokens []token.Token
+	start  token.Position
+}
+
+// startTable turns the paragraph b into a TABLE if it has a single line, the header,
+// and ln is a delimiter row with as many cells, such as `| --- | :-: |`.
+func (p *Parser) startTable(b *block, ln *line) bool {
+	for _, tok := range b.tokens {
+		if tok.Type == token.LINE_FEED_CODE {
+			return false
+		}
+	}
+	if ln.indent() >= 4 {
+		return false
+	}
+
+	saved := *ln
+	ln.skipIndent()
+	alignments, ok := parseDelimiterRow(trimTokens(ln.rest()))
+	if !ok || len(splitCells(b.tokens, b.node.Pos)) != len(alignments) {
+		*ln = saved
+		return false
+	}
+
+	b.node.Type = ast.TABLE
+	b.rows = []tableRow{{tokens: b.tokens, end: b.node.End}}
+	b.tokens = nil
+	b.alignments = alignments
+	b.node.End = ln.end
+	return true
+}
+
+// parseDelimiterRow returns the alignments of the cells of a delimiter row.
+// Each cell is a run of hyphens with an optional colon at either end, and the row has at least one pipe.
+func parseDelimiterRow(tokens []token.Token) ([]ast.Alignment, bool) {
+	if len(tokens) == 0 || bytes.IndexByte(tokensBytes(tokens), '|') < 0 {
+		return nil, false
+	}
+
+	var alignments []ast.Alignment
+	for _, cell := range splitCells(tokens, tokens[0].Pos) {
+		chs := bytes.TrimSpace(tokensBytes(cell.tokens))
+		left := bytes.HasPrefix(chs, []byte(":"))
+		right := bytes.HasSuffix(chs, []byte(":"))
+		chs = bytes.TrimPrefix(bytes.TrimSuffix(chs, []byte(":")), []byte(":"))
+		if len(chs) == 0 || len(bytes.Trim(chs, "-")) > 0 {
+			return nil, false
+		}
+
+		switch {
+		case left && right:
+			alignments = append(alignments, ast.ALIGN_CENTER)
+		case left:
+			alignments = append(alignments, ast.ALIGN_LEFT)
+		case right:
+			alignments = append(alignments, ast.ALIGN_RIGHT)
+		default:
+			alignments = append(alignments, ast.ALIGN_NONE)
+		}
+	}
+	return alignments, true
+}
+
+// splitCells splits the tokens of a row at the pipes; a pipe after a backslash is a part of the cell without the backslash.
+// The pipes at the start and the end of the row are optional.
+// start is the position of the row, where its first cell starts.
+func splitCells(tokens []token.Token, start token.Position) []tableCell {
+	cells := []tableCell{{start: start}}
+	for _, tok := range tokens {
+		if tok.Type != token.STRING || bytes.IndexByte(tok.Literal, '|') < 0 {
+			cells[len(cells)-1].tokens = append(cells[len(cells)-1].tokens, tok)
+			continue
+		}
+
+		// the literal is split into tokens of their own around the pipes
+		literal, pos := tok.Literal, tok.Pos
+		for len(literal) > 0 {
+			i := bytes.IndexByte(literal, '|')
+			if i < 0 {
+				i = len(literal)
+			}
+			escaped := i > 0 && i < len(literal) && literal[i-1] == '\\'
+			before := i
+			if escaped {
+				before--
+			}
+
+			cell := &cells[len(cells)-1]
+			if before > 0 {
+				cell.tokens = append(cell.tokens, token.Token{Type: token.STRING, Literal: literal[:before], Pos: pos})
+			}
+			if i == len(literal) {
+				break
+			}
+
+			pos = pos.Advance(literal[:i])
+			if escaped {
+				// the pipe starts the rest of the literal
+				literal = literal[i:]
+				cell.tokens = append(cell.tokens, token.Token{Type: token.STRING, Literal: literal[:1], Pos: pos})
+				pos = pos.Advance(literal[:1])
+				literal = literal[1:]
+				continue
+			}
+			pos = pos.Advance(literal[i : i+1])
+			literal = literal[i+1:]
+			cells = append(cells, tableCell{start: pos})
+		}
+	}
+
+	// the outer pipes leave an empty cell before the first one and after the last one
+	if len(cells) > 1 && len(trimTokens(cells[0].tokens)) == 0 {
+		cells = cells[1:]
+	}
+	if len(cells) > 1 && len(trimTokens(cells[len(cells)-1].tokens)) == 0 {
+		cells = cells[:len(cells)-1]
+	}
+	return cells
+}
+
+// finalizeTable builds the rows and the cells of the TABLE b.
+// Rows with fewer cells than the header are filled with empty cells, and the extra cells are dropped.
+func finalizeTable(b *block) {
+	for _, row := range b.rows {
+		rowNode := ast.NewNode(ast.TABLE_ROW)
+		rowNode.End = row.end
+		if len(row.tokens) > 0 {
+			rowNode.Pos = row.tokens[0].Pos
+		} else {
+			rowNode.Pos = row.end
+		}
+		b.node.AppendChild(rowNode)
+
+		cells := splitCells(row.tokens, rowNode.Pos)
+		for i, align := range b.alignments {
+			cell := ast.NewNode(ast.TABLE_CELL)
+			cell.Align = align
+			rowNode.AppendChild(cell)
+
+			if i >= len(cells) {
+				cell.Pos, cell.End = row.end, row.end
+				continue
+			}
+			tokens := trimCell(cells[i].tokens)
+			if len(tokens) == 0 {
+				cell.Pos, cell.End = cells[i].start, cells[i].start
+				continue
+			}
+			cell.Pos = tokens[0].Pos
+			cell.End = tokens[len(tokens)-1].End()
+			parseInlines(cell, tokens)
+		}
+	}
+}
+
+// trimCell removes the whitespace around the content of a cell,
+// including the tabs that are a part of a literal split at a pipe.
+func trimCell(tokens []token.Token) []token.Token {
+	tokens = trimTokens(tokens)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	tokens = append([]token.Token(nil), tokens...)
+	first := &tokens[0]
+	trimmed := bytes.TrimLeft(first.Literal, " \t")
+	first.Pos = first.Pos.Advance(first.Literal[:len(first.Literal)-len(trimmed)])
+	first.Literal = trimmed
+	last := &tokens[len(tokens)-1]
+	last.Literal = bytes.TrimRight(last.Literal, " \t")
+	return tokens
+}
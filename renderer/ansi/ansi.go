@@ -0,0 +1,539 @@
+// Package ansi renders a document tree for a terminal, styled with ANSI escape sequences.
+package ansi
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/text"
+)
+
+const (
+	reset  = "\x1b[0m"
+	bold   = "\x1b[1m"
+	faint  = "\x1b[2m"
+	italic = "\x1b[3m"
+
+	linkStyle      = "\x1b[4;34m"
+	codeStyle      = "\x1b[38;5;203;48;5;236m"
+	codeBlockStyle = "\x1b[48;5;236m"
+
+	// defaultWidth is used when the width of the terminal isn't known.
+	defaultWidth = 80
+)
+
+// headingStyles are the styles of headings by level; deeper headings use the last one.
+var headingStyles = []string{"\x1b[1;4;35m", "\x1b[1;36m", "\x1b[1;32m", bold}
+
+// bullets are the markers of unordered list items by the depth of the list.
+var bullets = []string{"•", "◦", "▪"}
+
+// span is a piece of inline text and the style it is written in.
+type span struct {
+	text  string
+	style string
+	link  string
+}
+
+// prefix is written at the start of each line inside a container block:
+// the gutter of a quote, or the marker and then the indentation of a list item.
+type prefix struct {
+	first string
+	rest  string
+	used  bool
+}
+
+// Renderer renders a document tree for a terminal.
+// Paragraphs and headings are wrapped at the width of the terminal
+// and links are written as OSC 8 hyperlinks.
+type Renderer struct {
+	width int
+
+	// started is true once a block was written.
+	started bool
+
+	prefixes []*prefix
+
+	// spans are the inlines of the paragraph, heading or table cell being rendered.
+	spans  []span
+	styles []string
+	links  []string
+
+	// rows are the cells of the table being rendered.
+	rows [][][]span
+}
+
+// NewRenderer initializes Renderer to wrap lines at width columns.
+func NewRenderer(width int) *Renderer {
+	if width <= 0 {
+		width = defaultWidth
+	}
+	return &Renderer{width: width}
+}
+
+// RenderNode implements renderer.Renderer.
+func (r *Renderer) RenderNode(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+	var result []byte
+	status := renderer.GoToNext
+
+	switch node.Type {
+	case ast.DOCUMENT:
+	case ast.HEADING, ast.PARAGRAPH:
+		if entering {
+			result = r.startBlock(result, node)
+			r.spans = r.spans[:0]
+			if node.Type == ast.HEADING {
+				r.styles = append(r.styles, headingStyle(node.Level))
+			}
+			break
+		}
+		if node.Type == ast.HEADING {
+			r.styles = r.styles[:len(r.styles)-1]
+		}
+		for _, line := range wrap(r.spans, r.width-r.prefixWidth()) {
+			result = r.appendLine(result, line)
+		}
+	case ast.BLOCK_QUOTE:
+		if entering {
+			gutter := faint + "│" + reset + " "
+			r.prefixes = append(r.prefixes, &prefix{first: gutter, rest: gutter})
+		} else {
+			r.prefixes = r.prefixes[:len(r.prefixes)-1]
+		}
+	case ast.LIST:
+	case ast.LIST_ITEM:
+		if entering {
+			marker := listMarker(node)
+			r.prefixes = append(r.prefixes, &prefix{first: marker, rest: strings.Repeat(" ", textWidth(marker))})
+			break
+		}
+		if !r.prefixes[len(r.prefixes)-1].used {
+			// an empty item is still written with its marker
+			result = r.startBlock(result, node)
+			result = r.appendLine(result, nil)
+		}
+		r.prefixes = r.prefixes[:len(r.prefixes)-1]
+	case ast.CODE_BLOCK:
+		if !entering {
+			break
+		}
+		result = r.startBlock(result, node)
+		width := r.width - r.prefixWidth()
+		code := strings.TrimSuffix(string(node.Literal), "\n")
+		for _, line := range strings.Split(code, "\n") {
+			line = expandTabs(stripControls(line))
+			if padding := width - 2 - textWidth(line); padding > 0 {
+				line += strings.Repeat(" ", padding)
+			}
+			result = r.appendLine(result, []span{{text: " " + line + " ", style: codeBlockStyle}})
+		}
+	case ast.HORIZON:
+		if entering {
+			result = r.startBlock(result, node)
+			result = r.appendLine(result, []span{{text: strings.Repeat("─", r.width-r.prefixWidth()), style: faint}})
+		}
+	case ast.TABLE:
+		if entering {
+			result = r.startBlock(result, node)
+			r.rows = r.rows[:0]
+			break
+		}
+		result = r.appendTable(result, node)
+	case ast.TABLE_ROW:
+		if entering {
+			r.rows = append(r.rows, nil)
+		}
+	case ast.TABLE_CELL:
+		header := node.Parent != nil && node.Parent.Parent != nil && node.Parent.Parent.Children[0] == node.Parent
+		if entering {
+			r.spans = r.spans[:0]
+			if header {
+				r.styles = append(r.styles, bold)
+			}
+			break
+		}
+		if header {
+			r.styles = r.styles[:len(r.styles)-1]
+		}
+		row := len(r.rows) - 1
+		r.rows[row] = append(r.rows[row], append([]span(nil), r.spans...))
+	case ast.TEXT:
+		if entering {
+			r.addSpan(string(node.Literal), "")
+		}
+	case ast.SOFT_BREAK:
+		if entering {
+			r.addSpan(" ", "")
+		}
+	case ast.CODE:
+		if entering {
+			r.addSpan(string(node.Literal), codeStyle)
+		}
+	case ast.EMPHASIS, ast.STRONG:
+		if !entering {
+			r.styles = r.styles[:len(r.styles)-1]
+		} else if node.Type == ast.EMPHASIS {
+			r.styles = append(r.styles, italic)
+		} else {
+			r.styles = append(r.styles, bold)
+		}
+	case ast.LINK:
+		if entering {
+			r.styles = append(r.styles, linkStyle)
+			r.links = append(r.links, stripControls(string(node.Destination)))
+		} else {
+			r.styles = r.styles[:len(r.styles)-1]
+			r.links = r.links[:len(r.links)-1]
+		}
+	case ast.IMAGE:
+		if entering {
+			r.links = append(r.links, stripControls(string(node.Destination)))
+			r.addSpan("[image: "+string(renderer.PlainText(nil, node))+"]", linkStyle)
+			r.links = r.links[:len(r.links)-1]
+			status = renderer.SkipChildren
+		}
+	default:
+		return renderer.Terminate, fmt.Errorf("unsupported node type: %q", node.Type)
+	}
+
+	if len(result) == 0 {
+		return status, nil
+	}
+	_, err := w.Write(result)
+	return status, err
+}
+
+// appendTable writes the rows of table with the columns aligned, and a rule under the header.
+// Tables are not wrapped.
+func (r *Renderer) appendTable(result []byte, table *ast.Node) []byte {
+	var widths []int
+	for _, row := range r.rows {
+		for i, cell := range row {
+			width := 0
+			for _, s := range cell {
+				width += textWidth(s.text)
+			}
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	separator := span{text: " │ ", style: faint}
+	for i, row := range r.rows {
+		var line []span
+		for j, cell := range row {
+			if j > 0 {
+				line = append(line, separator)
+			}
+			width := 0
+			for _, s := range cell {
+				width += textWidth(s.text)
+			}
+			left, right := 0, widths[j]-width
+			switch table.Children[i].Children[j].Align {
+			case ast.ALIGN_RIGHT:
+				left, right = right, 0
+			case ast.ALIGN_CENTER:
+				left, right = right/2, right-right/2
+			}
+			line = append(line, span{text: strings.Repeat(" ", left)})
+			line = append(line, cell...)
+			if j < len(row)-1 {
+				line = append(line, span{text: strings.Repeat(" ", right)})
+			}
+		}
+		result = r.appendLine(result, line)
+
+		if i == 0 {
+			var rule []string
+			for _, width := range widths {
+				rule = append(rule, strings.Repeat("─", width))
+			}
+			result = r.appendLine(result, []span{{text: strings.Join(rule, "─┼─"), style: faint}})
+		}
+	}
+	return result
+}
+
+func headingStyle(level int) string {
+	if level > len(headingStyles) {
+		level = len(headingStyles)
+	}
+	return headingStyles[level-1]
+}
+
+// listMarker returns the bullet or the number of a list item, followed by a space.
+func listMarker(item *ast.Node) string {
+	list := item.Parent
+	if list == nil {
+		return bullets[0] + " "
+	}
+	if list.Ordered {
+		for i, child := range list.Children {
+			if child == item {
+				return strconv.Itoa(list.Start+i) + string(list.Delimiter) + " "
+			}
+		}
+	}
+
+	depth := 0
+	for n := list.Parent; n != nil; n = n.Parent {
+		if n.Type == ast.LIST && !n.Ordered {
+			depth++
+		}
+	}
+	return bullets[depth%len(bullets)] + " "
+}
+
+// addSpan adds text to the paragraph or heading being rendered, in the current style.
+func (r *Renderer) addSpan(text string, style string) {
+	s := span{text: stripControls(text), style: strings.Join(r.styles, "") + style}
+	if len(r.links) > 0 {
+		s.link = r.links[len(r.links)-1]
+	}
+	r.spans = append(r.spans, s)
+}
+
+// startBlock separates a block from the one written before it by a blank line,
+// except for the blocks in a tight list and the first block of a list item.
+func (r *Renderer) startBlock(result []byte, node *ast.Node) []byte {
+	if r.started && separated(node) {
+		// the containers that start with node have nothing to write yet
+		var line []byte
+		for _, p := range r.prefixes {
+			if p.used {
+				line = appendStr(line, p.rest)
+			}
+		}
+		result = append(result, strings.TrimRight(string(line), " ")...)
+		result = append(result, '\n')
+	}
+	r.started = true
+	return result
+}
+
+// separated reports whether a blank line is written before node.
+func separated(node *ast.Node) bool {
+	parent := node.Parent
+	if parent == nil {
+		return true
+	}
+	first := parent.Children[0] == node
+
+	switch parent.Type {
+	case ast.LIST:
+		if first {
+			return separated(parent)
+		}
+		return !parent.Tight
+	case ast.LIST_ITEM:
+		if first {
+			return separated(parent)
+		}
+		return parent.Parent == nil || !parent.Parent.Tight
+	case ast.BLOCK_QUOTE:
+		if first {
+			return separated(parent)
+		}
+	}
+	return true
+}
+
+// appendLine writes a line of spans after the prefixes of the containers.
+func (r *Renderer) appendLine(result []byte, spans []span) []byte {
+	for _, p := range r.prefixes {
+		if p.used {
+			result = appendStr(result, p.rest)
+		} else {
+			result = appendStr(result, p.first)
+			p.used = true
+		}
+	}
+
+	// adjacent spans in the same style are written as one
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && merged[n-1].style == s.style && merged[n-1].link == s.link {
+			merged[n-1].text += s.text
+		} else {
+			merged = append(merged, s)
+		}
+	}
+
+	for _, s := range merged {
+		if s.link != "" {
+			// OSC 8 hyperlink
+			result = appendStr(result, "\x1b]8;;"+s.link+"\x1b\\")
+		}
+		if s.style != "" {
+			result = appendStr(result, s.style+s.text+reset)
+		} else {
+			result = appendStr(result, s.text)
+		}
+		if s.link != "" {
+			result = appendStr(result, "\x1b]8;;\x1b\\")
+		}
+	}
+	return append(result, '\n')
+}
+
+func (r *Renderer) prefixWidth() int {
+	width := 0
+	for _, p := range r.prefixes {
+		width += textWidth(p.rest)
+	}
+	return width
+}
+
+// word is a run of text that is never broken across lines.
+type word struct {
+	spans []span
+	width int
+	// space is the span the space before the word belongs to, or nil if the word follows the previous one directly.
+	space *span
+}
+
+// wrap breaks spans into lines of at most width columns at spaces and around wide characters.
+// A word that is wider than a line is put on a line of its own.
+func wrap(spans []span, width int) [][]span {
+	var lines [][]span
+	var line []span
+	lineWidth := 0
+	for _, w := range words(spans) {
+		separator := 0
+		if w.space != nil && lineWidth > 0 {
+			separator = 1
+		}
+		if lineWidth > 0 && lineWidth+separator+w.width > width {
+			lines = append(lines, line)
+			line, lineWidth, separator = nil, 0, 0
+		}
+		if separator > 0 {
+			line = append(line, span{text: " ", style: w.space.style, link: w.space.link})
+		}
+		line = append(line, w.spans...)
+		lineWidth += separator + w.width
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// words splits spans at spaces, and around wide characters which can be broken between without a space.
+func words(spans []span) []word {
+	var result []word
+	var current *word
+	var space *span
+
+	for i := range spans {
+		s := &spans[i]
+		for _, r := range s.text {
+			switch {
+			case r == ' ':
+				current = nil
+				space = s
+			case text.RuneWidth(r) == 2:
+				result = append(result, word{spans: []span{{text: string(r), style: s.style, link: s.link}}, width: 2, space: space})
+				current = nil
+				space = nil
+			default:
+				if current == nil {
+					result = append(result, word{space: space})
+					current = &result[len(result)-1]
+					space = nil
+				}
+				if n := len(current.spans); n > 0 && current.spans[n-1].style == s.style && current.spans[n-1].link == s.link {
+					current.spans[n-1].text += string(r)
+				} else {
+					current.spans = append(current.spans, span{text: string(r), style: s.style, link: s.link})
+				}
+				current.width += text.RuneWidth(r)
+			}
+		}
+	}
+	return result
+}
+
+// textWidth returns the number of columns s takes in a terminal, skipping escape sequences.
+func textWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			i = skipEscape(s, i)
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		width += text.RuneWidth(r)
+		i += size
+	}
+	return width
+}
+
+// skipEscape returns the index after the escape sequence at s[i].
+func skipEscape(s string, i int) int {
+	if i+1 >= len(s) {
+		return len(s)
+	}
+	switch s[i+1] {
+	case '[':
+		// CSI: ends with a byte in the range @ to ~
+		for i += 2; i < len(s); i++ {
+			if '@' <= s[i] && s[i] <= '~' {
+				return i + 1
+			}
+		}
+	case ']':
+		// OSC: ends with ST
+		if end := strings.Index(s[i:], "\x1b\\"); end >= 0 {
+			return i + end + 2
+		}
+	}
+	return len(s)
+}
+
+// stripControls removes the C0 and C1 control characters from s, except tabs,
+// so that a document can't end an escape sequence of the renderer or start one of its own.
+// Bytes that are not UTF-8 are replaced with U+FFFD.
+func stripControls(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || !unicode.IsControl(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// expandTabs replaces the tabs in line with spaces up to the next tab stop of 4.
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	column := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := 4 - column%4
+			b.WriteString(strings.Repeat(" ", n))
+			column += n
+			continue
+		}
+		b.WriteRune(r)
+		column += text.RuneWidth(r)
+	}
+	return b.String()
+}
+
+func appendStr(slice []byte, str string) []byte {
+	return append(slice, str...)
+}
@@ -0,0 +1,75 @@
+package ansi
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+type expected struct {
+	input string
+	want  string
+}
+
+func compareGotAndWant(t *testing.T, width int, tests []expected) {
+	t.Helper()
+	for i, tt := range tests {
+		var got bytes.Buffer
+		if err := parser.New(lexer.New([]byte(tt.input))).RenderTo(&got, NewRenderer(width)); err != nil {
+			t.Fatalf("tests[%d] - %v", i, err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("tests[%d] - output wrong.\nexpected=%q\ngot=%q", i, tt.want, got.String())
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []expected{
+		{"# Title\n\ntext\n", "\x1b[1;4;35mTitle\x1b[0m\n\ntext\n"},
+		{"*a* **b** `c`\n", "\x1b[3ma\x1b[0m \x1b[1mb\x1b[0m \x1b[38;5;203;48;5;236mc\x1b[0m\n"},
+		// the space between the words of a link is a part of the hyperlink
+		{"[a b](http://example.com)\n", "\x1b]8;;http://example.com\x1b\\\x1b[4;34ma b\x1b[0m\x1b]8;;\x1b\\\n"},
+		{"![a *b*](img.png)\n", "\x1b]8;;img.png\x1b\\\x1b[4;34m[image: a b]\x1b[0m\x1b]8;;\x1b\\\n"},
+		{"- a\n- b\n  - c\n-\n", "• a\n• b\n  ◦ c\n• \n"},
+		{"3) a\n\n4) b\n", "3) a\n\n4) b\n"},
+		{"> a\n>\n> - b\n", "\x1b[2m│\x1b[0m a\n\x1b[2m│\x1b[0m\n\x1b[2m│\x1b[0m • b\n"},
+		{"```\n\tx\n```\n", "\x1b[48;5;236m     x              \x1b[0m\n"},
+		{"| a | bb |\n| -: | :-: |\n| 1 |\n", "\x1b[1ma\x1b[0m\x1b[2m │ \x1b[0m\x1b[1mbb\x1b[0m\n\x1b[2m──┼───\x1b[0m\n1\x1b[2m │ \x1b[0m \n"},
+		{"***\n", "\x1b[2m────────────────────\x1b[0m\n"},
+	}
+	compareGotAndWant(t, 20, tests)
+}
+
+func TestRenderWrap(t *testing.T) {
+	tests := []expected{
+		{"aaa bbb ccc\nddd\n", "aaa bbb\nccc ddd\n"},
+		// a word wider than a line isn't broken
+		{"a abcdefghij b\n", "a\nabcdefghij\nb\n"},
+		// wide characters take two columns and can be broken between
+		{"日本語の文章\n", "日本語\nの文章\n"},
+		{"- aaa bbb\n", "• aaa\n  bbb\n"},
+		{"> aaa bbb\n", "\x1b[2m│\x1b[0m aaa\n\x1b[2m│\x1b[0m bbb\n"},
+	}
+	compareGotAndWant(t, 7, tests)
+}
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"abc", 3},
+		{"日本", 4},
+		{"é", 1},
+		{"\x1b[1mab\x1b[0m", 2},
+		{"\x1b]8;;http://example.com\x1b\\ab\x1b]8;;\x1b\\", 2},
+	}
+	for i, tt := range tests {
+		if got := textWidth(tt.input); got != tt.want {
+			t.Errorf("tests[%d] - width wrong. expected=%d, got=%d", i, tt.want, got)
+		}
+	}
+}
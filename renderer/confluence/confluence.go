@@ -0,0 +1,111 @@
+// Package confluence renders a document tree in the storage format of Confluence pages,
+// the XHTML of the html package with the macros of Confluence for code blocks and images.
+package confluence
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/html"
+)
+
+// languages maps the languages of renderer.CodeLanguage to the languages of the code macro.
+// Code blocks in other languages are written without one.
+var languages = map[string]string{
+	"bash":       "bash",
+	"cpp":        "cpp",
+	"csharp":     "c#",
+	"css":        "css",
+	"diff":       "diff",
+	"go":         "go",
+	"groovy":     "groovy",
+	"html":       "xml",
+	"xml":        "xml",
+	"java":       "java",
+	"javascript": "js",
+	"php":        "php",
+	"perl":       "perl",
+	"powershell": "powershell",
+	"python":     "py",
+	"ruby":       "ruby",
+	"scala":      "scala",
+	"sql":        "sql",
+	"yaml":       "yml",
+}
+
+// Renderer renders a document tree in the storage format.
+// Code blocks are written as code macros, and images as image macros: an image at a URL is shown from there,
+// and any other one is taken from the attachments of the page, which is warned about.
+// It keeps the warnings, and the last byte written as the html Renderer does.
+type Renderer struct {
+	*renderer.Overrides
+	warnings []renderer.Warning
+}
+
+// NewRenderer initializes Renderer.
+func NewRenderer() *Renderer {
+	r := &Renderer{Overrides: renderer.NewOverrides(html.NewXHTMLRenderer())}
+	r.Register(ast.CODE_BLOCK, r.renderCodeBlock)
+	r.Register(ast.IMAGE, r.renderImage)
+	return r
+}
+
+// Warnings implements renderer.Warner.
+func (r *Renderer) Warnings() []renderer.Warning {
+	return r.warnings
+}
+
+func (r *Renderer) renderCodeBlock(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+	if !entering {
+		return renderer.GoToNext, nil
+	}
+	var result []byte
+	result = appendStr(result, `<ac:structured-macro ac:name="code">`)
+	if language, ok := languages[renderer.CodeLanguage(node.Info)]; ok {
+		result = appendStr(result, `<ac:parameter ac:name="language">`+language+`</ac:parameter>`)
+	}
+	result = appendStr(result, "<ac:plain-text-body><![CDATA[")
+	// the end of a CDATA section is split between two of them
+	result = append(result, bytes.ReplaceAll(html.ValidXML(node.Literal), []byte("]]>"), []byte("]]]]><![CDATA[>"))...)
+	result = appendStr(result, "]]></ac:plain-text-body></ac:structured-macro>\n")
+	_, err := w.Write(result)
+	return renderer.GoToNext, err
+}
+
+func (r *Renderer) renderImage(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+	if !entering {
+		return renderer.SkipChildren, nil
+	}
+	var result []byte
+	result = appendStr(result, `<ac:image ac:alt="`)
+	result = html.AppendXMLEscaped(result, renderer.PlainText(nil, node))
+	result = appendStr(result, `">`)
+	if u, err := url.Parse(string(node.Destination)); err == nil && u.Scheme != "" {
+		result = appendStr(result, `<ri:url ri:value="`)
+		result = html.AppendXMLEscaped(result, node.Destination)
+	} else {
+		filename := path.Base(string(node.Destination))
+		if err == nil {
+			filename = path.Base(u.Path)
+		}
+		r.warnings = append(r.warnings, renderer.Warning{
+			Pos:     node.Pos,
+			Message: "image " + strconv.Quote(string(node.Destination)) + " has to be attached to the page as " + strconv.Quote(filename),
+		})
+		result = appendStr(result, `<ri:attachment ri:filename="`)
+		result = html.AppendXMLEscaped(result, []byte(filename))
+	}
+	result = appendStr(result, `" /></ac:image>`)
+	_, err := w.Write(result)
+	// the description is already written as the alt text
+	return renderer.SkipChildren, err
+}
+
+func appendStr(slice []byte, str string) []byte {
+	return append(slice, str...)
+}
@@ -0,0 +1,57 @@
+package confluence
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+type expected struct {
+	input string
+	want  string
+}
+
+func compareGotAndWant(t *testing.T, tests []expected) {
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		if err := parser.New(lexer.New([]byte(tt.input))).RenderTo(&buf, NewRenderer()); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("tests[%d] - storage format wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []expected{
+		{input: "# Heading1\n*a* & **b**\n", want: "<h1>Heading1</h1>\n<p><em>a</em> &amp; <strong>b</strong></p>\n"},
+		{input: "```python\nprint(\"]]>\")\n```\n", want: "<ac:structured-macro ac:name=\"code\"><ac:parameter ac:name=\"language\">py</ac:parameter>" +
+			"<ac:plain-text-body><![CDATA[print(\"]]]]><![CDATA[>\")\n]]></ac:plain-text-body></ac:structured-macro>\n"},
+		{input: "```\na\n```\n", want: "<ac:structured-macro ac:name=\"code\"><ac:plain-text-body><![CDATA[a\n]]></ac:plain-text-body></ac:structured-macro>\n"},
+		{input: "![a & b](https://example.com/a.png)\n", want: "<p><ac:image ac:alt=\"a &amp; b\"><ri:url ri:value=\"https://example.com/a.png\" /></ac:image></p>\n"},
+		{input: "![a](img/a.png)\n", want: "<p><ac:image ac:alt=\"a\"><ri:attachment ri:filename=\"a.png\" /></ac:image></p>\n"},
+		{input: "***\n", want: "<hr />\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestWarnings(t *testing.T) {
+	r := NewRenderer()
+	if err := parser.New(lexer.New([]byte("![a](https://example.com/a.png) ![b](img/b.png?v=1)\n"))).RenderTo(&bytes.Buffer{}, r); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, warning := range r.Warnings() {
+		got = append(got, warning.String())
+	}
+	want := []string{"1:33: image \"img/b.png?v=1\" has to be attached to the page as \"b.png\""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings wrong.\nexpected=%q\ngot=%q", want, got)
+	}
+}
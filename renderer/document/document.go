@@ -0,0 +1,173 @@
+// Package document renders a document tree as a complete html page, laid out by an html/template.
+// The built-in layout styles the page like GitHub in a light or a dark theme, with the code blocks highlighted.
+package document
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/highlight"
+)
+
+// DefaultTheme is the theme of a page unless another one is chosen.
+const DefaultTheme = "light"
+
+//go:embed layout.html page.css themes
+var files embed.FS
+
+var layout = template.Must(template.ParseFS(files, "layout.html"))
+
+// Page is the data a layout is executed with.
+type Page struct {
+	// Title is the title of the page.
+	Title string
+	// Theme is the name of the theme.
+	Theme string
+	// Style is the CSS of the theme, to be written in a style element.
+	Style template.CSS
+	// Body is the document as html.
+	Body template.HTML
+	// Meta are the fields of the front matter of the document, if it has one.
+	Meta map[string]string
+}
+
+// Options configures the page written by Render.
+type Options struct {
+	// Theme is the name of a built-in theme, "light" or "dark". It defaults to DefaultTheme.
+	Theme string
+	// Title is the title of the page. It defaults to the title field of the front matter,
+	// or else to the first heading of the document.
+	Title string
+	// Template lays out the page, executed with a Page. It defaults to the built-in layout.
+	Template *template.Template
+	// LineNumbers writes the numbers of the lines of code blocks in a gutter before them.
+	LineNumbers bool
+}
+
+// IsTheme reports whether name is the name of a built-in theme.
+func IsTheme(name string) bool {
+	_, err := fs.Stat(files, themePath(name))
+	return err == nil
+}
+
+// Render writes doc to w as a complete html page. meta are the fields of the front matter of doc,
+// as returned by SplitFrontMatter; it may be nil.
+func Render(w io.Writer, doc *ast.Node, meta map[string]string, opts Options) error {
+	if opts.Theme == "" {
+		opts.Theme = DefaultTheme
+	}
+	if opts.Title == "" {
+		opts.Title = Title(doc, meta)
+	}
+	if opts.Template == nil {
+		opts.Template = layout
+	}
+
+	if !IsTheme(opts.Theme) {
+		return fmt.Errorf("unknown theme %q", opts.Theme)
+	}
+	theme, err := files.ReadFile(themePath(opts.Theme))
+	if err != nil {
+		return err
+	}
+	style, err := files.ReadFile("page.css")
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	if err := renderer.Render(&body, highlight.NewRenderer(highlight.Options{LineNumbers: opts.LineNumbers}), doc); err != nil {
+		return err
+	}
+
+	return opts.Template.Execute(w, &Page{
+		Title: opts.Title,
+		Theme: opts.Theme,
+		Style: template.CSS(string(theme) + "\n" + string(style)),
+		Body:  template.HTML(body.String()),
+		Meta:  meta,
+	})
+}
+
+// SplitFrontMatter returns the fields of the YAML front matter at the start of src, between lines of "---",
+// and src with the front matter replaced by blank lines, so that the rest keeps its positions.
+// Only the fields with a value on their own line are returned, with the quotes of the value removed.
+// src without a front matter is returned as it is.
+func SplitFrontMatter(src []byte) (map[string]string, []byte) {
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	if !isDelimiter(lines[0], "---") {
+		return nil, src
+	}
+
+	end := len(lines[0])
+	for i, line := range lines[1:] {
+		end += len(line)
+		if !isDelimiter(line, "---") && !isDelimiter(line, "...") {
+			continue
+		}
+
+		meta := map[string]string{}
+		for _, field := range lines[1 : i+1] {
+			// indented lines belong to the values of other fields
+			if len(field) == 0 || field[0] == ' ' || field[0] == '\t' || field[0] == '#' {
+				continue
+			}
+			key, value, ok := strings.Cut(string(field), ":")
+			if !ok {
+				continue
+			}
+			if value = unquote(strings.TrimSpace(value)); value != "" {
+				meta[strings.TrimSpace(key)] = value
+			}
+		}
+		blank := bytes.Repeat([]byte("\n"), bytes.Count(src[:end], []byte("\n")))
+		return meta, append(blank, src[end:]...)
+	}
+	return nil, src
+}
+
+func isDelimiter(line []byte, delimiter string) bool {
+	return string(bytes.TrimRight(line, " \t\r\n")) == delimiter
+}
+
+// unquote removes the quotes of a YAML scalar.
+func unquote(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	switch {
+	case value[0] == '"' && value[len(value)-1] == '"':
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+	case value[0] == '\'' && value[len(value)-1] == '\'':
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	return value
+}
+
+func themePath(name string) string {
+	return "themes/" + name + ".css"
+}
+
+// Title returns the title of doc: the title field of its front matter meta, or else the text of its first heading.
+// It returns "" if doc has neither.
+func Title(doc *ast.Node, meta map[string]string) string {
+	if title := meta["title"]; title != "" {
+		return title
+	}
+	for _, node := range doc.Children {
+		if node.Type == ast.HEADING {
+			return strings.TrimSpace(string(renderer.PlainText(nil, node)))
+		}
+	}
+	return ""
+}
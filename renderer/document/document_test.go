@@ -0,0 +1,88 @@
+package document
+
+import (
+	"bytes"
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantMeta map[string]string
+		wantBody string
+	}{
+		{"# a\n", nil, "# a\n"},
+		{"---\ntitle: a\n---\n# b\n", map[string]string{"title": "a"}, "\n\n\n# b\n"},
+		{"---\r\ntitle: \"a: \\\"b\\\"\"\r\nlang: 'c''d'\r\n...\r\ne", map[string]string{"title": `a: "b"`, "lang": "c'd"}, "\n\n\n\ne"},
+		{"---\n# comment\ntags:\n  - a\nempty:\n---", map[string]string{}, "\n\n\n\n\n"},
+		// a thematic break without a closing line
+		{"---\ntitle: a\n", nil, "---\ntitle: a\n"},
+		{"----\ntitle: a\n----\n", nil, "----\ntitle: a\n----\n"},
+	}
+
+	for i, tt := range tests {
+		meta, body := SplitFrontMatter([]byte(tt.input))
+		if !reflect.DeepEqual(meta, tt.wantMeta) {
+			t.Errorf("tests[%d] - meta wrong.\nexpected=%q\ngot=%q", i, tt.wantMeta, meta)
+		}
+		if string(body) != tt.wantBody {
+			t.Errorf("tests[%d] - body wrong.\nexpected=%q\ngot=%q", i, tt.wantBody, body)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	layout := template.Must(template.New("test").Parse("{{.Title}}|{{.Theme}}|{{.Meta.lang}}|{{.Body}}"))
+	tests := []struct {
+		input string
+		opts  Options
+		want  string
+	}{
+		{"a\n\n## *b* & c\n\n# d\n", Options{Template: layout}, "b &amp; c|light||<p>a</p>\n<h2><em>b</em> &amp; c</h2>\n<h1>d</h1>\n"},
+		{"---\ntitle: e\nlang: ja\n---\n# b\n", Options{Theme: "dark", Template: layout}, "e|dark|ja|<h1>b</h1>\n"},
+		{"---\ntitle: e\n---\n# b\n", Options{Title: "f", Template: layout}, "f|light||<h1>b</h1>\n"},
+		{"a\n", Options{Template: layout}, "|light||<p>a</p>\n"},
+	}
+
+	for i, tt := range tests {
+		meta, body := SplitFrontMatter([]byte(tt.input))
+		var buf bytes.Buffer
+		if err := Render(&buf, parser.New(lexer.New(body)).ParseDocument(), meta, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("tests[%d] - page wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestRenderLayout(t *testing.T) {
+	for _, theme := range []string{"light", "dark"} {
+		var buf bytes.Buffer
+		doc := parser.New(lexer.New([]byte("# a < b\n"))).ParseDocument()
+		if err := Render(&buf, doc, map[string]string{"lang": "en"}, Options{Theme: theme}); err != nil {
+			t.Fatal(err)
+		}
+		got := buf.String()
+		for _, want := range []string{
+			"<!DOCTYPE html>\n<html lang=\"en\">\n",
+			"<title>a &lt; b</title>\n",
+			"<style>\n:root {\n  color-scheme: " + theme + ";\n",
+			"<body class=\"theme-" + theme + "\">\n<main class=\"markdown-body\">\n<h1>a &lt; b</h1>\n</main>\n</body>\n</html>\n",
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s - page doesn't contain %q.\ngot=%q", theme, want, got)
+			}
+		}
+	}
+
+	if err := Render(&bytes.Buffer{}, parser.New(lexer.New(nil)).ParseDocument(), nil, Options{Theme: "blue"}); err == nil {
+		t.Error("error is not returned for an unknown theme")
+	}
+}
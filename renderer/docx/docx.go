@@ -0,0 +1,399 @@
+// Package docx writes a document tree as a Word document in the Office Open XML format.
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+)
+
+// ContentType is the media type of a Word document.
+const ContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// Render writes doc to w as a Word document, a zip archive of the document, its styles,
+// the numbering of its lists and the relationships of its hyperlinks.
+// Headings use the styles Heading1 to Heading6, quotes Quote, and code blocks and code spans the monospace styles
+// Code and CodeChar. Images are written as links to them with their description.
+// Headings are bookmarked with ids like the anchors of GitHub, links to fragments like #name link to the bookmark name,
+// and links to relative paths are written as their text.
+func Render(w io.Writer, doc *ast.Node) error {
+	d := &document{ids: renderer.HeadingIDs(doc)}
+	body, err := d.appendBlocks(nil, doc.Children, paragraph{})
+	if err != nil {
+		return err
+	}
+	// Word doesn't open a document ending with a table
+	if n := len(doc.Children); n > 0 && doc.Children[n-1].Type == ast.TABLE {
+		body = appendStr(body, "<w:p/>")
+	}
+
+	z := zip.NewWriter(w)
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", packageRelationships},
+		{"word/document.xml", xmlHeader + `<w:document xmlns:w="` + mainNamespace + `" xmlns:r="` + relationshipNamespace + `"><w:body>` +
+			string(body) + `</w:body></w:document>`},
+		{"word/styles.xml", styles},
+		{"word/numbering.xml", d.numbering()},
+		{"word/_rels/document.xml.rels", d.relationships()},
+	}
+	for _, part := range parts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// document collects what the parts besides the document refer to.
+type document struct {
+	// hyperlinks are the absolute URLs of the links, whose relationships are rId3 and on.
+	hyperlinks [][]byte
+	// lists are the numbered lists, whose numberings are 2 and on. Bulleted lists share the numbering 1.
+	lists []*ast.Node
+	// ids are the bookmark names of the headings, which links to fragments like #name point to.
+	ids map[*ast.Node]string
+	// bookmarks is the number of bookmarks written, which are numbered from 0.
+	bookmarks int
+}
+
+// paragraph is the formatting a block gives to the paragraphs in it.
+type paragraph struct {
+	// style is the paragraph style, or empty for Normal.
+	style string
+	// numID is the numbering of the first paragraph of a list item, or 0.
+	numID int
+	// level is the nesting level of the list of numID.
+	level int
+	// indent is the left indent of the blocks after the first one in a list item, or 0.
+	indent int
+}
+
+// run is the formatting inlines give to the text in them.
+type run struct {
+	bold   bool
+	italic bool
+	// style is the character style, or empty.
+	style string
+}
+
+func (d *document) appendBlocks(result []byte, blocks []*ast.Node, p paragraph) ([]byte, error) {
+	for _, block := range blocks {
+		var err error
+		if result, err = d.appendBlock(result, block, p); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (d *document) appendBlock(result []byte, node *ast.Node, p paragraph) ([]byte, error) {
+	var err error
+	switch node.Type {
+	case ast.HEADING:
+		result = appendParagraphStart(result, paragraph{style: "Heading" + strconv.Itoa(node.Level)}, "")
+		id := strconv.Itoa(d.bookmarks)
+		d.bookmarks++
+		result = appendStr(result, `<w:bookmarkStart w:id="`+id+`" w:name="`)
+		result = appendEscaped(result, []byte(d.ids[node]))
+		result = appendStr(result, `"/>`)
+		if result, err = d.appendInlines(result, node.Children, run{}); err != nil {
+			return nil, err
+		}
+		result = appendStr(result, `<w:bookmarkEnd w:id="`+id+`"/></w:p>`)
+	case ast.PARAGRAPH:
+		result = appendParagraphStart(result, p, "")
+		if result, err = d.appendInlines(result, node.Children, run{}); err != nil {
+			return nil, err
+		}
+		result = appendStr(result, "</w:p>")
+	case ast.BLOCK_QUOTE:
+		return d.appendBlocks(result, node.Children, paragraph{style: "Quote", indent: p.indent})
+	case ast.LIST:
+		return d.appendList(result, node)
+	case ast.CODE_BLOCK:
+		p.style = "Code"
+		result = appendParagraphStart(result, p, "")
+		result = appendRun(result, bytes.TrimSuffix(node.Literal, []byte("\n")), run{})
+		result = appendStr(result, "</w:p>")
+	case ast.HORIZON:
+		result = appendParagraphStart(result, p, `<w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr>`)
+		result = appendStr(result, "</w:p>")
+	case ast.TABLE:
+		return d.appendTable(result, node)
+	default:
+		return nil, fmt.Errorf("unsupported node type: %q", node.Type)
+	}
+	return result, nil
+}
+
+// appendList writes the items of a list as numbered paragraphs. Only the first block of an item is numbered,
+// and the others are indented to its text.
+func (d *document) appendList(result []byte, list *ast.Node) ([]byte, error) {
+	numID := 1
+	if list.Ordered {
+		d.lists = append(d.lists, list)
+		numID = len(d.lists) + 1
+	}
+	level := listLevel(list)
+
+	for _, item := range list.Children {
+		numbered := paragraph{style: "ListParagraph", numID: numID, level: level}
+		if len(item.Children) == 0 {
+			result = appendParagraphStart(result, numbered, "")
+			result = appendStr(result, "</w:p>")
+			continue
+		}
+		var err error
+		if result, err = d.appendBlock(result, item.Children[0], numbered); err != nil {
+			return nil, err
+		}
+		rest := paragraph{style: "ListParagraph", indent: indent(level)}
+		if result, err = d.appendBlocks(result, item.Children[1:], rest); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// appendTable writes a table with its first row repeated as the header on each page.
+func (d *document) appendTable(result []byte, table *ast.Node) ([]byte, error) {
+	columns := 0
+	for _, row := range table.Children {
+		if len(row.Children) > columns {
+			columns = len(row.Children)
+		}
+	}
+
+	result = appendStr(result, `<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="0" w:type="auto"/></w:tblPr><w:tblGrid>`)
+	for i := 0; i < columns; i++ {
+		result = appendStr(result, "<w:gridCol/>")
+	}
+	result = appendStr(result, "</w:tblGrid>")
+
+	for i, row := range table.Children {
+		result = appendStr(result, "<w:tr>")
+		if i == 0 {
+			result = appendStr(result, "<w:trPr><w:tblHeader/></w:trPr>")
+		}
+		for j := 0; j < columns; j++ {
+			result = appendStr(result, "<w:tc>")
+			if j >= len(row.Children) {
+				result = appendStr(result, "<w:p/></w:tc>")
+				continue
+			}
+			cell := row.Children[j]
+			properties := ""
+			if align := justification(cell.Align); align != "" {
+				properties = `<w:jc w:val="` + align + `"/>`
+			}
+			result = appendParagraphStart(result, paragraph{}, properties)
+			var err error
+			if result, err = d.appendInlines(result, cell.Children, run{bold: i == 0}); err != nil {
+				return nil, err
+			}
+			result = appendStr(result, "</w:p></w:tc>")
+		}
+		result = appendStr(result, "</w:tr>")
+	}
+	return appendStr(result, "</w:tbl>"), nil
+}
+
+func (d *document) appendInlines(result []byte, nodes []*ast.Node, r run) ([]byte, error) {
+	for _, node := range nodes {
+		var err error
+		switch node.Type {
+		case ast.TEXT:
+			result = appendRun(result, node.Literal, r)
+		case ast.SOFT_BREAK:
+			result = appendRun(result, []byte(" "), r)
+		case ast.CODE:
+			code := r
+			code.style = "CodeChar"
+			result = appendRun(result, node.Literal, code)
+		case ast.EMPHASIS, ast.STRONG:
+			inner := r
+			if node.Type == ast.EMPHASIS {
+				inner.italic = true
+			} else {
+				inner.bold = true
+			}
+			if result, err = d.appendInlines(result, node.Children, inner); err != nil {
+				return nil, err
+			}
+		case ast.LINK, ast.IMAGE:
+			link := r
+			start, ok := d.hyperlinkStart(node.Destination)
+			if ok {
+				result = append(result, start...)
+				link.style = "Hyperlink"
+			}
+			if node.Type == ast.IMAGE && len(node.Children) == 0 {
+				result = appendRun(result, node.Destination, link)
+			} else if result, err = d.appendInlines(result, node.Children, link); err != nil {
+				return nil, err
+			}
+			if ok {
+				result = appendStr(result, "</w:hyperlink>")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported node type: %q", node.Type)
+		}
+	}
+	return result, nil
+}
+
+// hyperlinkStart returns the start tag of a hyperlink to destination: to the bookmark name for a fragment like #name,
+// or through a relationship for an absolute URL. A relative path is relative to the markdown file, which the document
+// doesn't keep, so ok is false for it and the link is written as its text.
+func (d *document) hyperlinkStart(destination []byte) (start []byte, ok bool) {
+	if bytes.HasPrefix(destination, []byte("#")) {
+		start = appendStr(start, `<w:hyperlink w:anchor="`)
+		start = appendEscaped(start, destination[1:])
+		return appendStr(start, `">`), true
+	}
+	if u, err := url.Parse(string(destination)); err != nil || u.Scheme == "" {
+		return nil, false
+	}
+	d.hyperlinks = append(d.hyperlinks, destination)
+	return appendStr(start, `<w:hyperlink r:id="rId`+strconv.Itoa(len(d.hyperlinks)+2)+`">`), true
+}
+
+// numbering returns the word/numbering.xml part, with a numbering for each numbered list starting at its first number.
+func (d *document) numbering() string {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader + `<w:numbering xmlns:w="` + mainNamespace + `">`)
+	b.WriteString(abstractNumberings)
+	b.WriteString(`<w:num w:numId="1"><w:abstractNumId w:val="` + strconv.Itoa(bulletAbstract) + `"/></w:num>`)
+	for i, list := range d.lists {
+		b.WriteString(`<w:num w:numId="` + strconv.Itoa(i+2) + `"><w:abstractNumId w:val="` + strconv.Itoa(decimalAbstract) + `"/>` +
+			`<w:lvlOverride w:ilvl="` + strconv.Itoa(listLevel(list)) + `"><w:startOverride w:val="` + strconv.Itoa(list.Start) + `"/></w:lvlOverride></w:num>`)
+	}
+	b.WriteString(`</w:numbering>`)
+	return b.String()
+}
+
+// relationships returns the word/_rels/document.xml.rels part: the styles, the numbering and the hyperlinks.
+func (d *document) relationships() string {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	b.WriteString(`<Relationship Id="rId1" Type="` + relationshipNamespace + `/styles" Target="styles.xml"/>`)
+	b.WriteString(`<Relationship Id="rId2" Type="` + relationshipNamespace + `/numbering" Target="numbering.xml"/>`)
+	for i, destination := range d.hyperlinks {
+		b.WriteString(`<Relationship Id="rId` + strconv.Itoa(i+3) + `" Type="` + relationshipNamespace + `/hyperlink" Target="`)
+		b.Write(appendEscaped(nil, destination))
+		b.WriteString(`" TargetMode="External"/>`)
+	}
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+// appendParagraphStart opens a paragraph with the formatting of p and the additional properties.
+func appendParagraphStart(result []byte, p paragraph, properties string) []byte {
+	if p.style == "" && p.numID == 0 && p.indent == 0 && properties == "" {
+		return appendStr(result, "<w:p>")
+	}
+	result = appendStr(result, "<w:p><w:pPr>")
+	if p.style != "" {
+		result = appendStr(result, `<w:pStyle w:val="`+p.style+`"/>`)
+	}
+	if p.numID != 0 {
+		result = appendStr(result, `<w:numPr><w:ilvl w:val="`+strconv.Itoa(p.level)+`"/><w:numId w:val="`+strconv.Itoa(p.numID)+`"/></w:numPr>`)
+	}
+	result = appendStr(result, properties)
+	if p.indent != 0 {
+		result = appendStr(result, `<w:ind w:left="`+strconv.Itoa(p.indent)+`"/>`)
+	}
+	return appendStr(result, "</w:pPr>")
+}
+
+// appendRun writes text in a run with the formatting of r. Tabs and line feeds in text are written as such.
+func appendRun(result []byte, text []byte, r run) []byte {
+	result = appendStr(result, "<w:r>")
+	if r.style != "" || r.bold || r.italic {
+		result = appendStr(result, "<w:rPr>")
+		if r.style != "" {
+			result = appendStr(result, `<w:rStyle w:val="`+r.style+`"/>`)
+		}
+		if r.bold {
+			result = appendStr(result, "<w:b/>")
+		}
+		if r.italic {
+			result = appendStr(result, "<w:i/>")
+		}
+		result = appendStr(result, "</w:rPr>")
+	}
+
+	start := 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && text[i] != '\t' && text[i] != '\n' && text[i] != '\r' {
+			continue
+		}
+		if i > start {
+			result = appendStr(result, `<w:t xml:space="preserve">`)
+			result = appendEscaped(result, text[start:i])
+			result = appendStr(result, "</w:t>")
+		}
+		if i < len(text) {
+			switch text[i] {
+			case '\t':
+				result = appendStr(result, "<w:tab/>")
+			case '\n':
+				result = appendStr(result, "<w:br/>")
+			}
+		}
+		start = i + 1
+	}
+	return appendStr(result, "</w:r>")
+}
+
+// appendEscaped appends chs to slice, escaping the characters that are special in XML
+// and replacing the ones it can't contain.
+func appendEscaped(slice []byte, chs []byte) []byte {
+	var b bytes.Buffer
+	xml.EscapeText(&b, chs)
+	return append(slice, b.Bytes()...)
+}
+
+// listLevel returns the number of lists list is nested in, up to the last level of Word.
+func listLevel(list *ast.Node) int {
+	level := 0
+	for parent := list.Parent; parent != nil; parent = parent.Parent {
+		if parent.Type == ast.LIST && level < levels-1 {
+			level++
+		}
+	}
+	return level
+}
+
+// justification returns the value of w:jc for an alignment, or empty for none.
+func justification(align ast.Alignment) string {
+	switch align {
+	case ast.ALIGN_LEFT:
+		return "left"
+	case ast.ALIGN_CENTER:
+		return "center"
+	case ast.ALIGN_RIGHT:
+		return "right"
+	default:
+		return ""
+	}
+}
+
+func appendStr(slice []byte, str string) []byte {
+	return append(slice, str...)
+}
@@ -0,0 +1,153 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+type expected struct {
+	input string
+	want  string
+}
+
+// render returns the parts of the Word document of input by name.
+func render(t *testing.T, input string) map[string]string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Render(&buf, parser.New(lexer.New([]byte(input))).ParseDocument()); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string]string{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = string(b)
+	}
+	return parts
+}
+
+func compareGotAndWant(t *testing.T, tests []expected) {
+	for i, tt := range tests {
+		document := render(t, tt.input)["word/document.xml"]
+		got := document[strings.Index(document, "<w:body>")+len("<w:body>") : strings.Index(document, "</w:body>")]
+		if got != tt.want {
+			t.Errorf("tests[%d] - body wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []expected{
+		{input: "# Heading1\n###### Heading6\n", want: `<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:bookmarkStart w:id="0" w:name="heading1"/><w:r><w:t xml:space="preserve">Heading1</w:t></w:r><w:bookmarkEnd w:id="0"/></w:p>` +
+			`<w:p><w:pPr><w:pStyle w:val="Heading6"/></w:pPr><w:bookmarkStart w:id="1" w:name="heading6"/><w:r><w:t xml:space="preserve">Heading6</w:t></w:r><w:bookmarkEnd w:id="1"/></w:p>`},
+		{input: "a <b>\nc\n", want: `<w:p><w:r><w:t xml:space="preserve">a &lt;b&gt;</w:t></w:r><w:r><w:t xml:space="preserve"> </w:t></w:r><w:r><w:t xml:space="preserve">c</w:t></w:r></w:p>`},
+		{input: "*a* **b *c*** `d`\n", want: `<w:p><w:r><w:rPr><w:i/></w:rPr><w:t xml:space="preserve">a</w:t></w:r><w:r><w:t xml:space="preserve"> </w:t></w:r>` +
+			`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">b </w:t></w:r><w:r><w:rPr><w:b/><w:i/></w:rPr><w:t xml:space="preserve">c</w:t></w:r>` +
+			`<w:r><w:t xml:space="preserve"> </w:t></w:r><w:r><w:rPr><w:rStyle w:val="CodeChar"/></w:rPr><w:t xml:space="preserve">d</w:t></w:r></w:p>`},
+		{input: "[a](https://example.com/)\n", want: `<w:p><w:hyperlink r:id="rId3"><w:r><w:rPr><w:rStyle w:val="Hyperlink"/></w:rPr><w:t xml:space="preserve">a</w:t></w:r></w:hyperlink></w:p>`},
+		{input: "[a](#b)\n", want: `<w:p><w:hyperlink w:anchor="b"><w:r><w:rPr><w:rStyle w:val="Hyperlink"/></w:rPr><w:t xml:space="preserve">a</w:t></w:r></w:hyperlink></w:p>`},
+		{input: "[a](b.md)\n", want: `<w:p><w:r><w:t xml:space="preserve">a</w:t></w:r></w:p>`},
+		{input: "> a\n", want: `<w:p><w:pPr><w:pStyle w:val="Quote"/></w:pPr><w:r><w:t xml:space="preserve">a</w:t></w:r></w:p>`},
+		{input: "- a\n  - b\n", want: `<w:p><w:pPr><w:pStyle w:val="ListParagraph"/><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">a</w:t></w:r></w:p>` +
+			`<w:p><w:pPr><w:pStyle w:val="ListParagraph"/><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">b</w:t></w:r></w:p>`},
+		{input: "1. a\n\n   b\n", want: `<w:p><w:pPr><w:pStyle w:val="ListParagraph"/><w:numPr><w:ilvl w:val="0"/><w:numId w:val="2"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">a</w:t></w:r></w:p>` +
+			`<w:p><w:pPr><w:pStyle w:val="ListParagraph"/><w:ind w:left="720"/></w:pPr><w:r><w:t xml:space="preserve">b</w:t></w:r></w:p>`},
+		{input: "```go\na\n\tb\n```\n", want: `<w:p><w:pPr><w:pStyle w:val="Code"/></w:pPr><w:r><w:t xml:space="preserve">a</w:t><w:br/><w:tab/><w:t xml:space="preserve">b</w:t></w:r></w:p>`},
+		{input: "***\n", want: `<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr></w:pPr></w:p>`},
+		{input: "| a | b |\n| --- | --: |\n| c |\n", want: `<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="0" w:type="auto"/></w:tblPr><w:tblGrid><w:gridCol/><w:gridCol/></w:tblGrid>` +
+			`<w:tr><w:trPr><w:tblHeader/></w:trPr><w:tc><w:p><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">a</w:t></w:r></w:p></w:tc>` +
+			`<w:tc><w:p><w:pPr><w:jc w:val="right"/></w:pPr><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">b</w:t></w:r></w:p></w:tc></w:tr>` +
+			`<w:tr><w:tc><w:p><w:r><w:t xml:space="preserve">c</w:t></w:r></w:p></w:tc><w:tc><w:p><w:pPr><w:jc w:val="right"/></w:pPr></w:p></w:tc></w:tr></w:tbl><w:p/>`},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestRenderBookmarks(t *testing.T) {
+	document := render(t, "# Set up\n\nSee [usage](#usage) and [again](#usage-1).\n\n## Usage\n## Usage\n")["word/document.xml"]
+
+	bookmarks := map[string]bool{}
+	var anchors []string
+	d := xml.NewDecoder(strings.NewReader(document))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range start.Attr {
+			switch {
+			case start.Name.Local == "bookmarkStart" && attr.Name.Local == "name":
+				bookmarks[attr.Value] = true
+			case start.Name.Local == "hyperlink" && attr.Name.Local == "anchor":
+				anchors = append(anchors, attr.Value)
+			}
+		}
+	}
+
+	if len(bookmarks) != 3 {
+		t.Errorf("bookmarks wrong. got=%v", bookmarks)
+	}
+	if len(anchors) != 2 {
+		t.Errorf("anchors wrong. got=%q", anchors)
+	}
+	for _, anchor := range anchors {
+		if !bookmarks[anchor] {
+			t.Errorf("bookmark %q of a link is missing. got=%v", anchor, bookmarks)
+		}
+	}
+}
+
+func TestRenderParts(t *testing.T) {
+	parts := render(t, "3. [a](https://example.com/?a=1&b=2) [c](#d) [e](f.md)\n")
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml", "word/styles.xml", "word/numbering.xml", "word/_rels/document.xml.rels"} {
+		part, ok := parts[name]
+		if !ok {
+			t.Errorf("%s is missing", name)
+			continue
+		}
+		d := xml.NewDecoder(strings.NewReader(part))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s is not well-formed: %v", name, err)
+				break
+			}
+		}
+	}
+
+	if want := `<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/?a=1&amp;b=2" TargetMode="External"/>`; !strings.Contains(parts["word/_rels/document.xml.rels"], want) {
+		t.Errorf("hyperlink relationship is missing.\nexpected=%q\ngot=%q", want, parts["word/_rels/document.xml.rels"])
+	}
+	if strings.Contains(parts["word/_rels/document.xml.rels"], `Id="rId4"`) {
+		t.Errorf("relationship of a fragment or a relative path is written.\ngot=%q", parts["word/_rels/document.xml.rels"])
+	}
+	if want := `<w:num w:numId="2"><w:abstractNumId w:val="1"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="3"/></w:lvlOverride></w:num>`; !strings.Contains(parts["word/numbering.xml"], want) {
+		t.Errorf("numbering is missing.\nexpected=%q\ngot=%q", want, parts["word/numbering.xml"])
+	}
+}
@@ -0,0 +1,107 @@
+package docx
+
+import (
+	"strconv"
+	"strings"
+)
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+// namespaces of the parts.
+const (
+	mainNamespace         = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	relationshipNamespace = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+)
+
+// contentTypes is the [Content_Types].xml part, the media types of the parts of the package.
+const contentTypes = xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>` +
+	`</Types>`
+
+// packageRelationships is the _rels/.rels part, which points to the document.
+const packageRelationships = xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="` + relationshipNamespace + `/officeDocument" Target="word/document.xml"/>` +
+	`</Relationships>`
+
+// headingSizes are the font sizes of the heading levels, in half-points.
+var headingSizes = []int{32, 28, 26, 24, 22, 22}
+
+// styles is the word/styles.xml part: the heading styles Heading1 to Heading6,
+// Quote, Code and ListParagraph for paragraphs, CodeChar and Hyperlink for runs, and TableGrid for tables.
+var styles = func() string {
+	var b strings.Builder
+	b.WriteString(xmlHeader + `<w:styles xmlns:w="` + mainNamespace + `">`)
+	b.WriteString(`<w:docDefaults>` +
+		`<w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:eastAsia="Calibri" w:cs="Calibri"/><w:sz w:val="22"/><w:szCs w:val="22"/></w:rPr></w:rPrDefault>` +
+		`<w:pPrDefault><w:pPr><w:spacing w:after="160" w:line="259" w:lineRule="auto"/></w:pPr></w:pPrDefault>` +
+		`</w:docDefaults>`)
+	b.WriteString(`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>`)
+	for i, size := range headingSizes {
+		level := strconv.Itoa(i + 1)
+		b.WriteString(`<w:style w:type="paragraph" w:styleId="Heading` + level + `"><w:name w:val="heading ` + level + `"/>` +
+			`<w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:uiPriority w:val="9"/><w:qFormat/>` +
+			`<w:pPr><w:keepNext/><w:spacing w:before="240" w:after="120"/><w:outlineLvl w:val="` + strconv.Itoa(i) + `"/></w:pPr>` +
+			`<w:rPr><w:b/><w:bCs/><w:sz w:val="` + strconv.Itoa(size) + `"/><w:szCs w:val="` + strconv.Itoa(size) + `"/></w:rPr></w:style>`)
+	}
+	b.WriteString(`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+		`<w:pPr><w:pBdr><w:left w:val="single" w:sz="18" w:space="8" w:color="D0D7DE"/></w:pBdr><w:ind w:left="360"/></w:pPr>` +
+		`<w:rPr><w:color w:val="57606A"/></w:rPr></w:style>`)
+	b.WriteString(`<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+		`<w:pPr><w:shd w:val="clear" w:color="auto" w:fill="F6F8FA"/><w:spacing w:line="240" w:lineRule="auto"/></w:pPr>` +
+		`<w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:eastAsia="Consolas" w:cs="Consolas"/><w:sz w:val="20"/><w:szCs w:val="20"/></w:rPr></w:style>`)
+	b.WriteString(`<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+		`<w:pPr><w:ind w:left="720"/><w:contextualSpacing/></w:pPr></w:style>`)
+	b.WriteString(`<w:style w:type="character" w:styleId="CodeChar"><w:name w:val="Code Char"/>` +
+		`<w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:eastAsia="Consolas" w:cs="Consolas"/><w:sz w:val="20"/><w:szCs w:val="20"/>` +
+		`<w:shd w:val="clear" w:color="auto" w:fill="F6F8FA"/></w:rPr></w:style>`)
+	b.WriteString(`<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/>` +
+		`<w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr></w:style>`)
+	b.WriteString(`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/>` +
+		`<w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:tblPr><w:tblBorders>`)
+	for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+		b.WriteString(`<w:` + side + ` w:val="single" w:sz="4" w:space="0" w:color="auto"/>`)
+	}
+	b.WriteString(`</w:tblBorders><w:tblCellMar><w:left w:w="108" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>`)
+	b.WriteString(`</w:styles>`)
+	return b.String()
+}()
+
+// bullets are the symbols of the nesting levels of bulleted lists.
+var bullets = []string{"•", "◦", "▪"}
+
+// levels is the number of nesting levels of lists Word supports.
+const levels = 9
+
+// abstract numberings of numbering.xml.
+const (
+	bulletAbstract = iota
+	decimalAbstract
+)
+
+// abstractNumberings are the definitions of bulleted and numbered lists in word/numbering.xml.
+var abstractNumberings = func() string {
+	var b strings.Builder
+	for _, abstract := range []int{bulletAbstract, decimalAbstract} {
+		b.WriteString(`<w:abstractNum w:abstractNumId="` + strconv.Itoa(abstract) + `"><w:multiLevelType w:val="hybridMultilevel"/>`)
+		for i := 0; i < levels; i++ {
+			format, text := "bullet", bullets[i%len(bullets)]
+			if abstract == decimalAbstract {
+				format, text = "decimal", "%"+strconv.Itoa(i+1)+"."
+			}
+			b.WriteString(`<w:lvl w:ilvl="` + strconv.Itoa(i) + `"><w:start w:val="1"/><w:numFmt w:val="` + format + `"/>` +
+				`<w:lvlText w:val="` + text + `"/><w:lvlJc w:val="left"/>` +
+				`<w:pPr><w:ind w:left="` + strconv.Itoa(indent(i)) + `" w:hanging="360"/></w:pPr></w:lvl>`)
+		}
+		b.WriteString(`</w:abstractNum>`)
+	}
+	return b.String()
+}()
+
+// indent returns the left indent of the items of a list at level, in twentieths of a point.
+func indent(level int) int {
+	return 720 * (level + 1)
+}
@@ -0,0 +1,408 @@
+// Package epub writes markdown documents as the chapters of an EPUB 3 book.
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/html"
+)
+
+// ContentType is the media type of an EPUB book.
+const ContentType = "application/epub+zip"
+
+// Chapter is a document of a book.
+type Chapter struct {
+	// Name is the slash-separated path of the markdown file of the chapter.
+	// The links of other chapters to it point to the chapter in the book, and its local images are read relative to it.
+	Name string
+	Doc  *ast.Node
+}
+
+// Options configures the book written by Render.
+type Options struct {
+	// Title is the title of the book. It defaults to the first heading of the first chapter.
+	Title string
+	// Language is the language of the book, such as `en`, which is the default.
+	Language string
+	// Identifier is the unique identifier of the book. It defaults to a UUID derived from the content.
+	Identifier string
+	// Modified is the time the book was last modified. It defaults to the current time.
+	Modified time.Time
+	// ReadFile returns the content of the local image at a slash-separated path.
+	// Without it, a chapter with a local image is an error.
+	ReadFile func(name string) ([]byte, error)
+}
+
+// imageTypes are the media types of the images that can be embedded, by extension.
+var imageTypes = map[string]string{
+	".gif":  "image/gif",
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+}
+
+// book is what the parts of a book refer to.
+type book struct {
+	opts     Options
+	chapters []*chapter
+	// images are the embedded images in the order they are first used.
+	images []*image
+	// imagesByName are the embedded images by their path.
+	imagesByName map[string]*image
+}
+
+type chapter struct {
+	Chapter
+	href  string
+	title string
+	// ids are the ids of the headings.
+	ids map[*ast.Node]string
+	// remote is true if the chapter has images outside the book.
+	remote bool
+}
+
+type image struct {
+	href      string
+	mediaType string
+	content   []byte
+}
+
+// Render writes chapters to w as an EPUB book: a content document for each chapter,
+// a navigation document listing the headings of the chapters, and the local images they show.
+// Headings are given ids like the anchors of GitHub, and the links between chapters are rewritten to point inside the book.
+func Render(w io.Writer, chapters []Chapter, opts Options) error {
+	if len(chapters) == 0 {
+		return fmt.Errorf("a book needs at least one chapter")
+	}
+	if opts.Language == "" {
+		opts.Language = "en"
+	}
+	if opts.Modified.IsZero() {
+		opts.Modified = time.Now()
+	}
+
+	b := &book{opts: opts, imagesByName: map[string]*image{}}
+	for i, ch := range chapters {
+		c := &chapter{
+			Chapter: ch,
+			href:    "chapter-" + strconv.Itoa(i+1) + ".xhtml",
+			title:   firstHeading(ch.Doc),
+			ids:     renderer.HeadingIDs(ch.Doc),
+		}
+		if c.title == "" {
+			c.title = strings.TrimSuffix(path.Base(ch.Name), path.Ext(ch.Name))
+		}
+		if err := b.addImages(c); err != nil {
+			return err
+		}
+		b.chapters = append(b.chapters, c)
+	}
+	if b.opts.Title == "" {
+		b.opts.Title = b.chapters[0].title
+	}
+
+	var contents [][]byte
+	for _, c := range b.chapters {
+		content, err := b.content(c)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+		contents = append(contents, content)
+	}
+	if b.opts.Identifier == "" {
+		b.opts.Identifier = identifier(contents)
+	}
+
+	z := zip.NewWriter(w)
+	// the media type comes first and uncompressed, so that the format can be recognized by its first bytes
+	f, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, ContentType); err != nil {
+		return err
+	}
+
+	type part struct {
+		name    string
+		content []byte
+	}
+	parts := []part{
+		{"META-INF/container.xml", []byte(container)},
+		{"EPUB/package.opf", b.packageDocument()},
+		{"EPUB/nav.xhtml", b.navigation()},
+	}
+	for i, c := range b.chapters {
+		parts = append(parts, part{"EPUB/" + c.href, contents[i]})
+	}
+	for _, img := range b.images {
+		parts = append(parts, part{"EPUB/" + img.href, img.content})
+	}
+	for _, part := range parts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(part.content); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// container is the META-INF/container.xml file, which points to the package document.
+const container = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="EPUB/package.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+
+// addImages embeds the local images of c.
+func (b *book) addImages(c *chapter) error {
+	return renderer.Walk(c.Doc, func(node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+		if node.Type != ast.IMAGE || !entering {
+			return renderer.GoToNext, nil
+		}
+		name, ok := localPath(c.Name, node.Destination)
+		if !ok {
+			// images in data URLs are in the book already
+			c.remote = c.remote || !bytes.HasPrefix(node.Destination, []byte("data:"))
+			return renderer.GoToNext, nil
+		}
+		if _, ok := b.imagesByName[name]; ok {
+			return renderer.GoToNext, nil
+		}
+
+		ext := strings.ToLower(path.Ext(name))
+		mediaType, ok := imageTypes[ext]
+		if !ok {
+			return renderer.Terminate, fmt.Errorf("%s: unsupported image type %q", c.Name, name)
+		}
+		if b.opts.ReadFile == nil {
+			return renderer.Terminate, fmt.Errorf("%s: image %q can't be read", c.Name, name)
+		}
+		content, err := b.opts.ReadFile(name)
+		if err != nil {
+			return renderer.Terminate, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		img := &image{href: "images/image-" + strconv.Itoa(len(b.images)+1) + ext, mediaType: mediaType, content: content}
+		b.images = append(b.images, img)
+		b.imagesByName[name] = img
+		return renderer.GoToNext, nil
+	})
+}
+
+// content returns the XHTML content document of c.
+func (b *book) content(c *chapter) ([]byte, error) {
+	base := html.NewXHTMLRenderer()
+	r := renderer.NewOverrides(base)
+	r.Register(ast.HEADING, func(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+		if !entering {
+			return base.RenderNode(w, node, entering)
+		}
+		_, err := io.WriteString(w, "<h"+strconv.Itoa(node.Level)+` id="`+c.ids[node]+`">`)
+		return renderer.GoToNext, err
+	})
+	r.Register(ast.LINK, func(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+		return withDestination(node, b.linkDestination(c, node.Destination), func() (renderer.WalkStatus, error) {
+			return base.RenderNode(w, node, entering)
+		})
+	})
+	r.Register(ast.IMAGE, func(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+		destination := node.Destination
+		if name, ok := localPath(c.Name, destination); ok {
+			destination = []byte(b.imagesByName[name].href)
+		}
+		return withDestination(node, destination, func() (renderer.WalkStatus, error) {
+			return base.RenderNode(w, node, entering)
+		})
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString(b.xhtmlStart(c.title))
+	if err := renderer.Render(&buf, r, c.Doc); err != nil {
+		return nil, err
+	}
+	buf.WriteString(xhtmlEnd)
+	return buf.Bytes(), nil
+}
+
+// linkDestination returns the destination in the book of a link in c: the content document of the chapter it points to,
+// or the destination as it is.
+func (b *book) linkDestination(c *chapter, destination []byte) []byte {
+	name, ok := localPath(c.Name, destination)
+	if !ok {
+		return destination
+	}
+	for _, other := range b.chapters {
+		if path.Clean(other.Name) != name {
+			continue
+		}
+		href := other.href
+		if u, err := url.Parse(string(destination)); err == nil && u.Fragment != "" {
+			href += "#" + u.EscapedFragment()
+		}
+		return []byte(href)
+	}
+	return destination
+}
+
+// withDestination calls fn with the destination of node replaced.
+func withDestination(node *ast.Node, destination []byte, fn func() (renderer.WalkStatus, error)) (renderer.WalkStatus, error) {
+	original := node.Destination
+	node.Destination = destination
+	defer func() { node.Destination = original }()
+	return fn()
+}
+
+// packageDocument returns the package document, the metadata, the manifest of the files and the order of the chapters.
+func (b *book) packageDocument() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="` + escape(b.opts.Language) + `">` + "\n")
+	buf.WriteString(`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	buf.WriteString(`<dc:identifier id="book-id">` + escape(b.opts.Identifier) + "</dc:identifier>\n")
+	buf.WriteString("<dc:title>" + escape(b.opts.Title) + "</dc:title>\n")
+	buf.WriteString("<dc:language>" + escape(b.opts.Language) + "</dc:language>\n")
+	buf.WriteString(`<meta property="dcterms:modified">` + b.opts.Modified.UTC().Format("2006-01-02T15:04:05Z") + "</meta>\n")
+	buf.WriteString("</metadata>\n<manifest>\n")
+	buf.WriteString(`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>` + "\n")
+	for i, c := range b.chapters {
+		buf.WriteString(`<item id="chapter-` + strconv.Itoa(i+1) + `" href="` + c.href + `" media-type="application/xhtml+xml"`)
+		if c.remote {
+			buf.WriteString(` properties="remote-resources"`)
+		}
+		buf.WriteString("/>\n")
+	}
+	for i, img := range b.images {
+		buf.WriteString(`<item id="image-` + strconv.Itoa(i+1) + `" href="` + img.href + `" media-type="` + img.mediaType + `"/>` + "\n")
+	}
+	buf.WriteString("</manifest>\n<spine>\n")
+	for i := range b.chapters {
+		buf.WriteString(`<itemref idref="chapter-` + strconv.Itoa(i+1) + `"/>` + "\n")
+	}
+	buf.WriteString("</spine>\n</package>\n")
+	return buf.Bytes()
+}
+
+// entry is an entry of the table of contents.
+type entry struct {
+	level    int
+	title    string
+	href     string
+	children []*entry
+}
+
+// navigation returns the navigation document, the table of contents nesting the headings of the chapters by their levels.
+// A chapter without headings is listed by its title.
+func (b *book) navigation() []byte {
+	root := &entry{}
+	stack := []*entry{root}
+	add := func(e *entry) {
+		for len(stack) > 1 && stack[len(stack)-1].level >= e.level {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, e)
+		stack = append(stack, e)
+	}
+	for _, c := range b.chapters {
+		headings := 0
+		for _, node := range c.Doc.Children {
+			if node.Type != ast.HEADING {
+				continue
+			}
+			title := strings.TrimSpace(string(renderer.PlainText(nil, node)))
+			if title == "" {
+				continue
+			}
+			add(&entry{level: node.Level, title: title, href: c.href + "#" + c.ids[node]})
+			headings++
+		}
+		if headings == 0 {
+			add(&entry{level: 1, title: c.title, href: c.href})
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(b.xhtmlStart(b.opts.Title))
+	buf.WriteString(`<nav epub:type="toc" id="toc">` + "\n<h1>" + escape(b.opts.Title) + "</h1>\n")
+	writeEntries(&buf, root.children)
+	buf.WriteString("</nav>\n")
+	buf.WriteString(xhtmlEnd)
+	return buf.Bytes()
+}
+
+func writeEntries(buf *bytes.Buffer, entries []*entry) {
+	buf.WriteString("<ol>\n")
+	for _, e := range entries {
+		buf.WriteString(`<li><a href="` + escape(e.href) + `">` + escape(e.title) + "</a>")
+		if len(e.children) > 0 {
+			buf.WriteString("\n")
+			writeEntries(buf, e.children)
+		}
+		buf.WriteString("</li>\n")
+	}
+	buf.WriteString("</ol>\n")
+}
+
+// xhtmlStart returns the start of an XHTML document up to its body.
+func (b *book) xhtmlStart(title string) string {
+	language := escape(b.opts.Language)
+	return `<?xml version="1.0" encoding="UTF-8"?>` + "\n<!DOCTYPE html>\n" +
+		`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="` + language + `" xml:lang="` + language + `">` + "\n" +
+		"<head>\n<title>" + escape(title) + "</title>\n</head>\n<body>\n"
+}
+
+const xhtmlEnd = "</body>\n</html>\n"
+
+// firstHeading returns the text of the first heading of doc, or empty if it has none.
+func firstHeading(doc *ast.Node) string {
+	for _, node := range doc.Children {
+		if node.Type == ast.HEADING {
+			return strings.TrimSpace(string(renderer.PlainText(nil, node)))
+		}
+	}
+	return ""
+}
+
+// localPath returns the path of destination relative to the chapter name, if it is a relative URL.
+func localPath(name string, destination []byte) (string, bool) {
+	u, err := url.Parse(string(destination))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+		return "", false
+	}
+	return path.Join(path.Dir(name), u.Path), true
+}
+
+// identifier returns a UUID URN derived from the content documents.
+func identifier(contents [][]byte) string {
+	h := sha1.New()
+	for _, content := range contents {
+		h.Write(content)
+	}
+	sum := h.Sum(nil)
+	// version 5 and the variant of RFC 4122
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func escape(s string) string {
+	return string(html.AppendXMLEscaped(nil, []byte(s)))
+}
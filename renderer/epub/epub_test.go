@@ -0,0 +1,166 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+func parseChapter(name string, input string) Chapter {
+	return Chapter{Name: name, Doc: parser.New(lexer.New([]byte(input))).ParseDocument()}
+}
+
+// render returns the files of the book of chapters by name, in the order they are in the archive.
+func render(t *testing.T, chapters []Chapter, opts Options) ([]string, map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Render(&buf, chapters, opts); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	files := map[string]string{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, f.Name)
+		files[f.Name] = string(b)
+	}
+	if z.File[0].Name != "mimetype" || z.File[0].Method != zip.Store {
+		t.Errorf("mimetype is not the first file stored uncompressed")
+	}
+	return names, files
+}
+
+// checkWellFormed reports an error if content of the file name doesn't parse as XML.
+func checkWellFormed(t *testing.T, name string, content string) {
+	t.Helper()
+	d := xml.NewDecoder(strings.NewReader(content))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Errorf("%s is not well-formed: %v", name, err)
+			return
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	chapters := []Chapter{
+		parseChapter("docs/intro.md", "# Intro\n\nSee [setup](setup.md#install) and ![logo](img/logo.png).\n\n## Goals\n## Goals\n"),
+		parseChapter("docs/setup.md", "Text only, ![remote](https://example.com/a.png) and ![again](../docs/img/logo.png).\n"),
+	}
+	var read []string
+	opts := Options{
+		Modified: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		ReadFile: func(name string) ([]byte, error) {
+			read = append(read, name)
+			return []byte("png"), nil
+		},
+	}
+	names, files := render(t, chapters, opts)
+
+	want := []string{"mimetype", "META-INF/container.xml", "EPUB/package.opf", "EPUB/nav.xhtml", "EPUB/chapter-1.xhtml", "EPUB/chapter-2.xhtml", "EPUB/images/image-1.png"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("files wrong.\nexpected=%q\ngot=%q", want, names)
+	}
+	for _, name := range names[1:] {
+		if strings.HasSuffix(name, ".png") {
+			continue
+		}
+		checkWellFormed(t, name, files[name])
+	}
+	// the image is read once for both chapters
+	if len(read) != 1 || read[0] != "docs/img/logo.png" {
+		t.Errorf("images read wrong. got=%q", read)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"EPUB/package.opf", "<dc:title>Intro</dc:title>"},
+		{"EPUB/package.opf", `<meta property="dcterms:modified">2024-01-02T03:04:05Z</meta>`},
+		{"EPUB/package.opf", `<item id="chapter-2" href="chapter-2.xhtml" media-type="application/xhtml+xml" properties="remote-resources"/>`},
+		{"EPUB/package.opf", `<item id="image-1" href="images/image-1.png" media-type="image/png"/>`},
+		{"EPUB/nav.xhtml", "<li><a href=\"chapter-1.xhtml#intro\">Intro</a>\n<ol>\n<li><a href=\"chapter-1.xhtml#goals\">Goals</a></li>\n<li><a href=\"chapter-1.xhtml#goals-1\">Goals</a></li>\n</ol>\n</li>\n<li><a href=\"chapter-2.xhtml\">setup</a></li>\n"},
+		{"EPUB/chapter-1.xhtml", `<h1 id="intro">Intro</h1>`},
+		{"EPUB/chapter-1.xhtml", `<a href="chapter-2.xhtml#install">setup</a> and <img src="images/image-1.png" alt="logo" />`},
+		{"EPUB/chapter-2.xhtml", `<img src="https://example.com/a.png" alt="remote" /> and <img src="images/image-1.png" alt="again" />`},
+	}
+	for i, tt := range tests {
+		if !strings.Contains(files[tt.name], tt.want) {
+			t.Errorf("tests[%d] - %s wrong.\nexpected to contain=%q\ngot=%q", i, tt.name, tt.want, files[tt.name])
+		}
+	}
+}
+
+func TestRenderControlCharacters(t *testing.T) {
+	// characters that XML doesn't allow, in headings, text, code and attributes
+	chapters := []Chapter{
+		parseChapter("a.md", "# Page\fbreak\n\na\fb\x01c \x1b[1m `\x0b` [link](http://example.com/ \"ti\x02tle\")\n\n```\n\x00\x08\n```\n"),
+		parseChapter("b.md", "| a\x03 |\n| - |\n| \xff |\n"),
+	}
+	names, files := render(t, chapters, Options{})
+	for _, name := range names[1:] {
+		checkWellFormed(t, name, files[name])
+	}
+	if want := "<h1 id=\"pagebreak\">Page\ufffdbreak</h1>"; !strings.Contains(files["EPUB/chapter-1.xhtml"], want) {
+		t.Errorf("chapter wrong.\nexpected to contain=%q\ngot=%q", want, files["EPUB/chapter-1.xhtml"])
+	}
+}
+
+func TestRenderIdentifier(t *testing.T) {
+	chapters := []Chapter{parseChapter("a.md", "a\n")}
+	_, files := render(t, chapters, Options{Title: "Book", Identifier: "urn:isbn:0000"})
+	if want := `<dc:identifier id="book-id">urn:isbn:0000</dc:identifier>`; !strings.Contains(files["EPUB/package.opf"], want) {
+		t.Errorf("identifier wrong.\nexpected to contain=%q\ngot=%q", want, files["EPUB/package.opf"])
+	}
+
+	// the default identifier depends only on the content
+	_, first := render(t, chapters, Options{Modified: time.Unix(0, 0)})
+	_, second := render(t, chapters, Options{Modified: time.Unix(1, 0)})
+	identifier := func(opf string) string {
+		return opf[strings.Index(opf, "<dc:identifier"):strings.Index(opf, "</dc:identifier>")]
+	}
+	if identifier(first["EPUB/package.opf"]) != identifier(second["EPUB/package.opf"]) {
+		t.Error("identifier differs for the same content")
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	tests := []struct {
+		chapters []Chapter
+		opts     Options
+	}{
+		{nil, Options{}},
+		{[]Chapter{parseChapter("a.md", "![a](a.png)\n")}, Options{}},
+		{[]Chapter{parseChapter("a.md", "![a](a.bmp)\n")}, Options{ReadFile: func(string) ([]byte, error) { return nil, nil }}},
+		{[]Chapter{parseChapter("a.md", "![a](a.png)\n")}, Options{ReadFile: func(string) ([]byte, error) { return nil, errors.New("not found") }}},
+	}
+
+	for i, tt := range tests {
+		if err := Render(io.Discard, tt.chapters, tt.opts); err == nil {
+			t.Errorf("tests[%d] - error is not returned", i)
+		}
+	}
+}
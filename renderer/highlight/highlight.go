@@ -0,0 +1,306 @@
+// Package highlight splits code into tokens for syntax highlighting, and renders a document tree as html
+// with the code blocks highlighted.
+package highlight
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/istsh/markdown-viewer/renderer"
+)
+
+// Class is the kind of a token, written as the class "tok-" + Class of its span.
+type Class string
+
+// The classes of tokens. Plain tokens are written without a span.
+const (
+	Plain    Class = ""
+	Keyword  Class = "keyword"
+	Type     Class = "type"
+	Literal  Class = "literal"
+	String   Class = "string"
+	Number   Class = "number"
+	Comment  Class = "comment"
+	Property Class = "property"
+	Variable Class = "variable"
+	Meta     Class = "meta"
+	Inserted Class = "inserted"
+	Deleted  Class = "deleted"
+)
+
+// Token is a piece of code.
+type Token struct {
+	Class Class
+	Text  []byte
+}
+
+// Tokenize splits code in language, the first word of the info string of a code block, into tokens.
+// The texts of the tokens make up code. It returns nil if the language is not known.
+func Tokenize(language string, code []byte) []Token {
+	lang, ok := languages[renderer.CodeLanguage([]byte(language))]
+	if !ok {
+		return nil
+	}
+	if lang.tokenize != nil {
+		return lang.tokenize(code)
+	}
+	return lang.scan(code)
+}
+
+// quote delimits a string.
+type quote struct {
+	delimiter string
+	// escapes is true if a backslash escapes the next character.
+	escapes bool
+	// multiline is true if the string may go on over line feeds.
+	multiline bool
+	// doubled is true if a doubled delimiter stands for itself, as in SQL.
+	doubled bool
+}
+
+// end returns the end of the string starting at i.
+func (q quote) end(code []byte, i int) int {
+	j := i + len(q.delimiter)
+	for j < len(code) {
+		switch {
+		case q.escapes && code[j] == '\\':
+			j += 2
+			continue
+		case bytes.HasPrefix(code[j:], []byte(q.delimiter)):
+			j += len(q.delimiter)
+			if !q.doubled || !bytes.HasPrefix(code[j:], []byte(q.delimiter)) {
+				return j
+			}
+		case code[j] == '\n' && !q.multiline:
+			return j
+		}
+		j++
+	}
+	return len(code)
+}
+
+// language describes the tokens of a language to the generic scanner,
+// or tokenizes it with a function of its own.
+type language struct {
+	keywords map[string]bool
+	types    map[string]bool
+	literals map[string]bool
+	// ignoreCase is true if the words are looked up in lower case, as in SQL.
+	ignoreCase bool
+	// identChars are the characters other than letters, digits and underscores in identifiers.
+	identChars string
+
+	lineComments []string
+	// commentAtWordStart is true if a line comment starts only where a word could, as in shell scripts.
+	commentAtWordStart bool
+	// blockComment are the start and the end of a block comment, if the language has one.
+	blockComment [2]string
+
+	// quotes start strings; the longer delimiters are listed first.
+	quotes []quote
+	// stringPrefixes are the words that may start a string, like r in Python.
+	stringPrefixes map[string]bool
+	// properties is true if a string followed by a colon is a key, as in JSON.
+	properties bool
+	// variables is true if $name is a variable, as in shell scripts.
+	variables bool
+	// decorators is true if @name is a decorator, as in Python.
+	decorators bool
+
+	tokenize func(code []byte) []Token
+}
+
+// tokens collects tokens, joining the neighbouring ones of the same class.
+type tokens []Token
+
+func (ts *tokens) add(class Class, text []byte) {
+	if len(text) == 0 {
+		return
+	}
+	if n := len(*ts); n > 0 && (*ts)[n-1].Class == class {
+		(*ts)[n-1].Text = append((*ts)[n-1].Text, text...)
+		return
+	}
+	*ts = append(*ts, Token{Class: class, Text: append([]byte(nil), text...)})
+}
+
+// scan tokenizes code with the generic scanner.
+func (lang *language) scan(code []byte) []Token {
+	var ts tokens
+	for i := 0; i < len(code); {
+		ch := code[i]
+		rest := code[i:]
+
+		if lang.isLineComment(code, i) {
+			end := bytes.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			ts.add(Comment, rest[:end])
+			i += end
+			continue
+		}
+		if start, end := lang.blockComment[0], lang.blockComment[1]; start != "" && bytes.HasPrefix(rest, []byte(start)) {
+			n := bytes.Index(rest[len(start):], []byte(end))
+			if n < 0 {
+				n = len(rest)
+			} else {
+				n += len(start) + len(end)
+			}
+			ts.add(Comment, rest[:n])
+			i += n
+			continue
+		}
+		if q, ok := lang.quoteAt(rest); ok {
+			end := q.end(code, i)
+			ts.add(lang.stringClass(code, end), code[i:end])
+			i = end
+			continue
+		}
+		if lang.variables && ch == '$' {
+			if end := variableEnd(code, i); end > i+1 {
+				ts.add(Variable, code[i:end])
+				i = end
+				continue
+			}
+		}
+		if lang.decorators && ch == '@' && i+1 < len(code) && lang.isIdentStart(code[i+1]) {
+			end := lang.identEnd(code, i+1)
+			ts.add(Meta, code[i:end])
+			i = end
+			continue
+		}
+		if isDigit(ch) {
+			end := numberEnd(code, i)
+			ts.add(Number, code[i:end])
+			i = end
+			continue
+		}
+		if lang.isIdentStart(ch) {
+			end := lang.identEnd(code, i)
+			word := string(code[i:end])
+			if lang.ignoreCase {
+				word = strings.ToLower(word)
+			}
+			if q, ok := lang.quoteAt(code[end:]); ok && lang.stringPrefixes[strings.ToLower(word)] {
+				end = q.end(code, end)
+				ts.add(String, code[i:end])
+				i = end
+				continue
+			}
+			ts.add(lang.wordClass(word), code[i:end])
+			i = end
+			continue
+		}
+		ts.add(Plain, rest[:1])
+		i++
+	}
+	return ts
+}
+
+func (lang *language) isLineComment(code []byte, i int) bool {
+	for _, prefix := range lang.lineComments {
+		if !bytes.HasPrefix(code[i:], []byte(prefix)) {
+			continue
+		}
+		if !lang.commentAtWordStart || i == 0 {
+			return true
+		}
+		switch code[i-1] {
+		case ' ', '\t', '\n', ';', '(', '|', '&':
+			return true
+		}
+	}
+	return false
+}
+
+func (lang *language) quoteAt(rest []byte) (quote, bool) {
+	for _, q := range lang.quotes {
+		if bytes.HasPrefix(rest, []byte(q.delimiter)) {
+			return q, true
+		}
+	}
+	return quote{}, false
+}
+
+// stringClass returns the class of the string ending at end: a property if a colon follows it in a language of keys.
+func (lang *language) stringClass(code []byte, end int) Class {
+	if lang.properties {
+		if rest := bytes.TrimLeft(code[end:], " \t"); len(rest) > 0 && rest[0] == ':' {
+			return Property
+		}
+	}
+	return String
+}
+
+func (lang *language) wordClass(word string) Class {
+	switch {
+	case lang.keywords[word]:
+		return Keyword
+	case lang.types[word]:
+		return Type
+	case lang.literals[word]:
+		return Literal
+	}
+	return Plain
+}
+
+func (lang *language) isIdentStart(ch byte) bool {
+	return ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch >= 0x80 ||
+		strings.IndexByte(lang.identChars, ch) >= 0
+}
+
+func (lang *language) identEnd(code []byte, i int) int {
+	for i < len(code) && (lang.isIdentStart(code[i]) || isDigit(code[i])) {
+		i++
+	}
+	return i
+}
+
+// variableEnd returns the end of the shell variable starting at i, like $name, ${name} or $1,
+// or i+1 if none starts there.
+func variableEnd(code []byte, i int) int {
+	j := i + 1
+	if j >= len(code) {
+		return j
+	}
+	switch ch := code[j]; {
+	case ch == '{':
+		if n := bytes.IndexByte(code[j:], '}'); n >= 0 {
+			return j + n + 1
+		}
+	case ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z':
+		for j < len(code) && (code[j] == '_' || 'a' <= code[j] && code[j] <= 'z' || 'A' <= code[j] && code[j] <= 'Z' || isDigit(code[j])) {
+			j++
+		}
+		return j
+	case isDigit(ch) || strings.IndexByte("@*#?$!-", ch) >= 0:
+		return j + 1
+	}
+	return i + 1
+}
+
+// numberEnd returns the end of the number starting at i, such as 42, 0x2a, 1_000 or 1.5e-3.
+func numberEnd(code []byte, i int) int {
+	hex := bytes.HasPrefix(code[i:], []byte("0x")) || bytes.HasPrefix(code[i:], []byte("0X"))
+	j := i
+	for j < len(code) {
+		ch := code[j]
+		switch {
+		case isDigit(ch) || ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z':
+			j++
+			if (ch == 'e' || ch == 'E') && !hex && j < len(code) && (code[j] == '+' || code[j] == '-') {
+				j++
+			}
+		case ch == '.' && j+1 < len(code) && isDigit(code[j+1]):
+			j++
+		default:
+			return j
+		}
+	}
+	return j
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
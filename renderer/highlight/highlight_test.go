@@ -0,0 +1,97 @@
+package highlight
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		language string
+		input    string
+		want     string
+	}{
+		{"go", "func f() string { return `a\nb` // c\n}",
+			`<span class="tok-keyword">func</span> f() <span class="tok-type">string</span> { <span class="tok-keyword">return</span> ` +
+				"<span class=\"tok-string\">`a\nb`</span> <span class=\"tok-comment\">// c</span>\n}"},
+		{"Go", `x := 0x1F + 1.5e-3 /* "a" */ + 'b' + nil`,
+			`x := <span class="tok-number">0x1F</span> + <span class="tok-number">1.5e-3</span> <span class="tok-comment">/* &quot;a&quot; */</span> + ` +
+				`<span class="tok-string">'b'</span> + <span class="tok-literal">nil</span>`},
+		{"go", `"a\"b`, `<span class="tok-string">&quot;a\&quot;b</span>`},
+		{"python", "@dec\ndef f(): return rb'\\'' + \"\"\"a\nb\"\"\" # c",
+			`<span class="tok-meta">@dec</span>` + "\n" + `<span class="tok-keyword">def</span> f(): <span class="tok-keyword">return</span> ` +
+				`<span class="tok-string">rb'\''</span> + <span class="tok-string">&quot;&quot;&quot;a` + "\n" + `b&quot;&quot;&quot;</span> <span class="tok-comment"># c</span>`},
+		{"js", "const $a = `${b}` ?? undefined",
+			"<span class=\"tok-keyword\">const</span> $a = <span class=\"tok-string\">`${b}`</span> ?? <span class=\"tok-literal\">undefined</span>"},
+		{"ts", "let a: number", `<span class="tok-keyword">let</span> a: <span class="tok-type">number</span>`},
+		{"sh", `if [ "$1" ]; then echo $HOME ${x} a#b; fi # c`,
+			`<span class="tok-keyword">if</span> [ <span class="tok-string">&quot;$1&quot;</span> ]; <span class="tok-keyword">then</span> ` +
+				`<span class="tok-keyword">echo</span> <span class="tok-variable">$HOME</span> <span class="tok-variable">${x}</span> a#b; ` +
+				`<span class="tok-keyword">fi</span> <span class="tok-comment"># c</span>`},
+		{"sql", "Select 'it''s' from t where a is null -- c",
+			`<span class="tok-keyword">Select</span> <span class="tok-string">'it''s'</span> <span class="tok-keyword">from</span> t ` +
+				`<span class="tok-keyword">where</span> a <span class="tok-keyword">is</span> <span class="tok-literal">null</span> <span class="tok-comment">-- c</span>`},
+		{"json", `{"a": ["b", -1, true]}`,
+			`{<span class="tok-property">&quot;a&quot;</span>: [<span class="tok-string">&quot;b&quot;</span>, -<span class="tok-number">1</span>, <span class="tok-literal">true</span>]}`},
+		{"yaml", "---\na: 1 # c\n- 'b': &x !t yes\nc: |\n  d: e\n\nf: \"g\"\n",
+			`<span class="tok-meta">---</span>` + "\n" +
+				`<span class="tok-property">a</span>: <span class="tok-number">1</span> <span class="tok-comment"># c</span>` + "\n" +
+				`- <span class="tok-property">'b'</span>: <span class="tok-variable">&amp;x</span> <span class="tok-type">!t</span> <span class="tok-literal">yes</span>` + "\n" +
+				`<span class="tok-property">c</span>: <span class="tok-meta">|</span>` + "\n" +
+				`<span class="tok-string">  d: e</span>` + "\n\n" +
+				`<span class="tok-property">f</span>: <span class="tok-string">&quot;g&quot;</span>` + "\n"},
+		{"diff", "--- a\n+++ b\n@@ -1 +1 @@\n-c\n+d\n e\n",
+			`<span class="tok-meta">--- a</span>` + "\n" + `<span class="tok-meta">+++ b</span>` + "\n" + `<span class="tok-meta">@@ -1 +1 @@</span>` + "\n" +
+				`<span class="tok-deleted">-c</span>` + "\n" + `<span class="tok-inserted">+d</span>` + "\n e\n"},
+	}
+
+	for i, tt := range tests {
+		tokens := Tokenize(tt.language, []byte(tt.input))
+		var text []byte
+		for _, token := range tokens {
+			text = append(text, token.Text...)
+		}
+		if string(text) != tt.input {
+			t.Errorf("tests[%d] - tokens don't make up the code.\nexpected=%q\ngot=%q", i, tt.input, text)
+		}
+		if got := string(appendTokens(nil, tokens)); got != tt.want {
+			t.Errorf("tests[%d] - tokens wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+
+	if tokens := Tokenize("cobol", []byte("a")); tokens != nil {
+		t.Errorf("tokens are returned for an unknown language: %v", tokens)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  Options
+		want  string
+	}{
+		{"```go\nvar a\n```\n", Options{}, "<pre><code class=\"language-go\"><span class=\"tok-keyword\">var</span> a\n</code></pre>\n"},
+		{"```cobol\n<a>\n```\n", Options{}, "<pre><code class=\"language-cobol\">&lt;a&gt;\n</code></pre>\n"},
+		{"```go {2, 4-9, x}\n/* a\nb */\nc\nd\n```\n", Options{},
+			"<pre><code class=\"language-go\"><span class=\"line\"><span class=\"tok-comment\">/* a</span>\n</span>" +
+				"<span class=\"line highlighted\"><span class=\"tok-comment\">b */</span>\n</span>" +
+				"<span class=\"line\">c\n</span><span class=\"line highlighted\">d\n</span></code></pre>\n"},
+		{"```\na\n\n```\n", Options{LineNumbers: true},
+			"<pre><code><span class=\"line\"><span class=\"line-number\" data-line=\"1\"></span>a\n</span>" +
+				"<span class=\"line\"><span class=\"line-number\" data-line=\"2\"></span>\n</span></code></pre>\n"},
+		{"a\n\n```\n```\n", Options{LineNumbers: true}, "<p>a</p>\n<pre><code></code></pre>\n"},
+	}
+
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		if err := parser.New(lexer.New([]byte(tt.input))).RenderTo(&buf, NewRenderer(tt.opts)); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("tests[%d] - html wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
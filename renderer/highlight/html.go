@@ -0,0 +1,154 @@
+package highlight
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/html"
+)
+
+// Options configures the code blocks written by Renderer.
+type Options struct {
+	// LineNumbers writes the number of each line of a code block in a gutter before it.
+	LineNumbers bool
+}
+
+// Renderer renders a document tree as html with the code blocks highlighted.
+// The tokens of code in a known language are written in spans of classes like tok-keyword,
+// and the lines marked after the language in the info string, as in "go {1,3-5}", are written in spans of class
+// "line highlighted". With line numbers or marked lines, each line is written in a span of class "line".
+// It keeps the last byte written, as the html Renderer does.
+type Renderer struct {
+	*renderer.Overrides
+	base *html.Renderer
+	opts Options
+}
+
+// NewRenderer initializes Renderer.
+func NewRenderer(opts Options) *Renderer {
+	r := &Renderer{base: html.NewRenderer(), opts: opts}
+	r.Overrides = renderer.NewOverrides(r.base)
+	r.Register(ast.CODE_BLOCK, r.renderCodeBlock)
+	return r
+}
+
+func (r *Renderer) renderCodeBlock(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+	language := renderer.InfoWord(node.Info)
+	tokens := Tokenize(string(language), node.Literal)
+	marked := markedLines(node.Info[len(language):], bytes.Count(node.Literal, []byte("\n"))+1)
+	lines := r.opts.LineNumbers || len(marked) > 0
+	if !entering || tokens == nil && !lines {
+		return r.base.RenderNode(w, node, entering)
+	}
+	if tokens == nil {
+		tokens = []Token{{Text: node.Literal}}
+	}
+
+	var result []byte
+	result = appendStr(result, "<pre><code")
+	if len(language) > 0 {
+		result = appendStr(result, ` class="language-`)
+		result = html.AppendEscaped(result, language)
+		result = appendStr(result, `"`)
+	}
+	result = appendStr(result, ">")
+	for i, line := range splitLines(tokens) {
+		if lines {
+			n := i + 1
+			result = appendStr(result, `<span class="line`)
+			if marked[n] {
+				result = appendStr(result, " highlighted")
+			}
+			result = appendStr(result, `">`)
+			if r.opts.LineNumbers {
+				// the number is shown by the style, so that it isn't copied with the code
+				result = appendStr(result, `<span class="line-number" data-line="`+strconv.Itoa(n)+`"></span>`)
+			}
+		}
+		result = appendTokens(result, line)
+		// the line feed is part of the line, which is a block
+		result = appendStr(result, "\n")
+		if lines {
+			result = appendStr(result, "</span>")
+		}
+	}
+	result = appendStr(result, "</code></pre>\n")
+	_, err := w.Write(result)
+	return renderer.GoToNext, err
+}
+
+// splitLines splits tokens into the tokens of each line, without the line feeds.
+func splitLines(tokens []Token) [][]Token {
+	lines := [][]Token{nil}
+	for _, token := range tokens {
+		for i, text := range bytes.Split(token.Text, []byte("\n")) {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+			if len(text) > 0 {
+				lines[len(lines)-1] = append(lines[len(lines)-1], Token{Class: token.Class, Text: text})
+			}
+		}
+	}
+	// the code ends with a line feed
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func appendTokens(result []byte, tokens []Token) []byte {
+	for _, token := range tokens {
+		if token.Class == Plain {
+			result = html.AppendEscaped(result, token.Text)
+			continue
+		}
+		result = appendStr(result, `<span class="tok-`+string(token.Class)+`">`)
+		result = html.AppendEscaped(result, token.Text)
+		result = appendStr(result, "</span>")
+	}
+	return result
+}
+
+// markedLines returns the numbers of the lines marked in the rest of an info string, like {1,3-5},
+// up to count. Numbers that can't be read are left out.
+func markedLines(attrs []byte, count int) map[int]bool {
+	attrs = bytes.TrimSpace(attrs)
+	if !bytes.HasPrefix(attrs, []byte("{")) {
+		return nil
+	}
+	end := bytes.IndexByte(attrs, '}')
+	if end < 0 {
+		return nil
+	}
+
+	marked := map[int]bool{}
+	for _, part := range strings.Split(string(attrs[1:end]), ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			continue
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+				continue
+			}
+		}
+		if to > count {
+			to = count
+		}
+		for n := from; n <= to; n++ {
+			marked[n] = true
+		}
+	}
+	return marked
+}
+
+func appendStr(slice []byte, str string) []byte {
+	return append(slice, str...)
+}
@@ -0,0 +1,294 @@
+package highlight
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+func words(s string) map[string]bool {
+	m := map[string]bool{}
+	for _, word := range strings.Fields(s) {
+		m[word] = true
+	}
+	return m
+}
+
+var golang = &language{
+	keywords: words(`break case chan const continue default defer else fallthrough for func go goto if import
+		interface map package range return select struct switch type var`),
+	types: words(`any bool byte comparable complex64 complex128 error float32 float64 int int8 int16 int32 int64
+		rune string uint uint8 uint16 uint32 uint64 uintptr`),
+	literals:     words("true false nil iota"),
+	lineComments: []string{"//"},
+	blockComment: [2]string{"/*", "*/"},
+	quotes: []quote{
+		{delimiter: `"`, escapes: true},
+		{delimiter: "'", escapes: true},
+		{delimiter: "`", multiline: true},
+	},
+}
+
+var python = &language{
+	keywords: words(`and as assert async await break class continue def del elif else except finally for from
+		global if import in is lambda nonlocal not or pass raise return try while with yield match case`),
+	types: words(`bool bytearray bytes complex dict float frozenset int list object set str tuple type
+		Exception print len range`),
+	literals:     words("True False None"),
+	lineComments: []string{"#"},
+	quotes: []quote{
+		{delimiter: `"""`, escapes: true, multiline: true},
+		{delimiter: "'''", escapes: true, multiline: true},
+		{delimiter: `"`, escapes: true},
+		{delimiter: "'", escapes: true},
+	},
+	stringPrefixes: words("r u b f br rb fr rf"),
+	decorators:     true,
+}
+
+var javascript = &language{
+	keywords: words(`async await break case catch class const continue debugger default delete do else export
+		extends finally for from function get if import in instanceof let new of return set static super switch
+		this throw try typeof var void while with yield`),
+	types:        words("Array Boolean Date Error Map Number Object Promise RegExp Set String Symbol"),
+	literals:     words("true false null undefined NaN Infinity"),
+	identChars:   "$",
+	lineComments: []string{"//"},
+	blockComment: [2]string{"/*", "*/"},
+	quotes: []quote{
+		{delimiter: `"`, escapes: true},
+		{delimiter: "'", escapes: true},
+		{delimiter: "`", escapes: true, multiline: true},
+	},
+}
+
+var typescript = &language{
+	keywords: words(`abstract as async await break case catch class const continue debugger declare default
+		delete do else enum export extends finally for from function get if implements import in infer
+		instanceof interface is keyof let namespace new of private protected public readonly return satisfies
+		set static super switch this throw try type typeof var void while with yield`),
+	types: words(`any bigint boolean never number object string symbol unknown
+		Array Boolean Date Error Map Number Object Promise Record RegExp Set String Symbol`),
+	literals:     javascript.literals,
+	identChars:   javascript.identChars,
+	lineComments: javascript.lineComments,
+	blockComment: javascript.blockComment,
+	quotes:       javascript.quotes,
+}
+
+var shell = &language{
+	keywords: words(`if then else elif fi for while until do done case esac in function select time
+		break continue return exit export local readonly declare unset source alias cd echo printf read set shift
+		test eval exec trap`),
+	identChars:         "-",
+	lineComments:       []string{"#"},
+	commentAtWordStart: true,
+	quotes: []quote{
+		{delimiter: `"`, escapes: true, multiline: true},
+		{delimiter: "'", multiline: true},
+	},
+	variables: true,
+}
+
+var sql = &language{
+	keywords: words(`add all alter and as asc begin between by case check column commit constraint create
+		cross default delete desc distinct drop else end exists foreign from full group having if in index inner
+		insert intersect into is join key left like limit not offset on or order outer primary references
+		returning right rollback select set table then transaction union unique update using values view when
+		where with`),
+	types: words(`bigint binary bit blob boolean char date datetime decimal double float int integer interval
+		json jsonb numeric real serial smallint text time timestamp uuid varchar`),
+	literals:     words("true false null"),
+	ignoreCase:   true,
+	lineComments: []string{"--"},
+	blockComment: [2]string{"/*", "*/"},
+	quotes:       []quote{{delimiter: "'", doubled: true, multiline: true}},
+}
+
+var json = &language{
+	literals:     words("true false null"),
+	quotes:       []quote{{delimiter: `"`, escapes: true}},
+	properties:   true,
+	lineComments: []string{"//"},
+	blockComment: [2]string{"/*", "*/"},
+}
+
+// languages are the languages by the names of renderer.CodeLanguage.
+var languages = map[string]*language{
+	"go":         golang,
+	"python":     python,
+	"javascript": javascript,
+	"jsx":        javascript,
+	"typescript": typescript,
+	"tsx":        typescript,
+	"bash":       shell,
+	"sql":        sql,
+	"json":       json,
+	"yaml":       {tokenize: tokenizeYAML},
+	"diff":       {tokenize: tokenizeDiff},
+}
+
+// tokenizeDiff tokenizes a unified diff line by line.
+func tokenizeDiff(code []byte) []Token {
+	var ts tokens
+	for _, line := range bytes.SplitAfter(code, []byte("\n")) {
+		text := bytes.TrimRight(line, "\r\n")
+		class := Plain
+		switch {
+		case hasAnyPrefix(text, "+++ ", "--- ", "diff ", "index ", "@@"):
+			class = Meta
+		case hasAnyPrefix(text, "+"):
+			class = Inserted
+		case hasAnyPrefix(text, "-"):
+			class = Deleted
+		}
+		ts.add(class, text)
+		ts.add(Plain, line[len(text):])
+	}
+	return ts
+}
+
+// yamlLiterals are the plain scalars of YAML that aren't strings.
+var yamlLiterals = words("true false null ~ yes no on off True False Null TRUE FALSE NULL Yes No On Off")
+
+// tokenizeYAML tokenizes YAML line by line. The keys of mappings are properties, the lines of block scalars strings,
+// and the other scalars are told apart by their values.
+func tokenizeYAML(code []byte) []Token {
+	var ts tokens
+	// block is the indentation of the key of the block scalar the lines are in, or -1.
+	block := -1
+	for _, line := range bytes.SplitAfter(code, []byte("\n")) {
+		text := bytes.TrimRight(line, "\r\n")
+		content := bytes.TrimLeft(text, " \t")
+		indent := len(text) - len(content)
+
+		if block >= 0 && (len(content) == 0 || indent > block) {
+			ts.add(String, text)
+			ts.add(Plain, line[len(text):])
+			continue
+		}
+		block = -1
+
+		ts.add(Plain, text[:indent])
+		switch {
+		case len(content) == 0:
+		case content[0] == '#':
+			ts.add(Comment, content)
+		case string(text) == "---" || string(text) == "...":
+			ts.add(Meta, content)
+		default:
+			// the dashes of sequence entries come before their keys
+			for bytes.HasPrefix(content, []byte("- ")) || string(content) == "-" {
+				n := len(content) - len(bytes.TrimLeft(content[1:], " "))
+				ts.add(Plain, content[:n])
+				content = content[n:]
+			}
+			key := yamlKeyEnd(content)
+			if key > 0 {
+				ts.add(Property, content[:key])
+				ts.add(Plain, content[key:key+1])
+				content = content[key+1:]
+			}
+			if yamlValue(&ts, content) {
+				block = indent
+			}
+		}
+		ts.add(Plain, line[len(text):])
+	}
+	return ts
+}
+
+// yamlKeyEnd returns the position of the colon after the key content starts with, or 0 if it doesn't start with one.
+func yamlKeyEnd(content []byte) int {
+	end := 0
+	if len(content) > 0 && (content[0] == '"' || content[0] == '\'') {
+		end = quote{delimiter: string(content[0]), escapes: content[0] == '"', doubled: content[0] == '\''}.end(content, 0)
+		if end < len(content) && content[end] == ':' && (end+1 == len(content) || content[end+1] == ' ') {
+			return end
+		}
+		return 0
+	}
+	for i := 0; i < len(content); i++ {
+		switch {
+		case content[i] == '#' && i > 0 && content[i-1] == ' ':
+			return 0
+		case content[i] == ':' && (i+1 == len(content) || content[i+1] == ' ' || content[i+1] == '\t'):
+			return i
+		}
+	}
+	return 0
+}
+
+// yamlValue adds the tokens of the value after a key or a dash, and reports whether it starts a block scalar.
+func yamlValue(ts *tokens, content []byte) bool {
+	value := bytes.TrimLeft(content, " \t")
+	ts.add(Plain, content[:len(content)-len(value)])
+	if len(value) == 0 {
+		return false
+	}
+
+	var comment []byte
+	switch value[0] {
+	case '"', '\'':
+		end := quote{delimiter: string(value[0]), escapes: value[0] == '"', doubled: value[0] == '\''}.end(value, 0)
+		ts.add(String, value[:end])
+		value = value[end:]
+		if i := bytes.IndexByte(value, '#'); i >= 0 {
+			value, comment = value[:i], value[i:]
+		}
+		ts.add(Plain, value)
+		ts.add(Comment, comment)
+		return false
+	case '|', '>':
+		ts.add(Meta, value)
+		return true
+	case '#':
+		ts.add(Comment, value)
+		return false
+	case '&', '*', '!':
+		// anchors, aliases and tags come before the value
+		n := bytes.IndexAny(value, " \t")
+		if n < 0 {
+			n = len(value)
+		}
+		class := Variable
+		if value[0] == '!' {
+			class = Type
+		}
+		ts.add(class, value[:n])
+		return yamlValue(ts, value[n:])
+	}
+
+	if i := bytes.Index(value, []byte(" #")); i >= 0 {
+		value, comment = value[:i+1], value[i+1:]
+	}
+	scalar := bytes.TrimRight(value, " \t")
+	class := Plain
+	switch {
+	case yamlLiterals[string(scalar)]:
+		class = Literal
+	case isYAMLNumber(string(scalar)):
+		class = Number
+	}
+	ts.add(class, scalar)
+	ts.add(Plain, value[len(scalar):])
+	ts.add(Comment, comment)
+	return false
+}
+
+func isYAMLNumber(s string) bool {
+	if _, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil && !strings.ContainsAny(s, "xXpP") && strings.ToLower(s) != "nan" && !strings.Contains(strings.ToLower(s), "inf")
+}
+
+func hasAnyPrefix(text []byte, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(text, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
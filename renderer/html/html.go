@@ -0,0 +1,342 @@
+// Package html renders a document tree as html.
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+)
+
+// Renderer renders a document tree as html.
+// It keeps the last byte it wrote, to start blocks on a new line.
+type Renderer struct {
+	// last is the last byte written, used to start blocks on a new line.
+	last byte
+	// xhtml is true when the output has to be well-formed XML.
+	xhtml bool
+}
+
+// NewRenderer initializes Renderer.
+func NewRenderer() *Renderer {
+	return &Renderer{}
+}
+
+// NewXHTMLRenderer initializes Renderer for the XML syntax of html, as in EPUB:
+// void elements are closed, and presentational attributes are written as styles.
+func NewXHTMLRenderer() *Renderer {
+	return &Renderer{xhtml: true}
+}
+
+// RenderNode implements renderer.Renderer.
+func (r *Renderer) RenderNode(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+	var result []byte
+	status := renderer.GoToNext
+
+	switch node.Type {
+	case ast.DOCUMENT:
+	case ast.HEADING:
+		level := strconv.Itoa(node.Level)
+		if entering {
+			result = r.cr(result)
+			result = appendStr(result, "<h"+level+">")
+		} else {
+			result = appendStr(result, "</h"+level+">\n")
+		}
+	case ast.PARAGRAPH:
+		if node.Parent != nil && node.Parent.Type == ast.LIST_ITEM && node.Parent.Parent != nil && node.Parent.Parent.Tight {
+			// paragraphs in a tight list are not wrapped
+			break
+		}
+		if entering {
+			result = r.cr(result)
+			result = appendStr(result, "<p>")
+		} else {
+			result = appendStr(result, "</p>\n")
+		}
+	case ast.BLOCK_QUOTE:
+		result = r.cr(result)
+		if entering {
+			result = appendStr(result, "<blockquote>\n")
+		} else {
+			result = appendStr(result, "</blockquote>\n")
+		}
+	case ast.LIST:
+		tag := "ul"
+		if node.Ordered {
+			tag = "ol"
+		}
+		result = r.cr(result)
+		if entering {
+			result = appendStr(result, "<"+tag)
+			if node.Ordered && node.Start != 1 {
+				result = appendStr(result, " start=\""+strconv.Itoa(node.Start)+"\"")
+			}
+			result = appendStr(result, ">\n")
+		} else {
+			result = appendStr(result, "</"+tag+">\n")
+		}
+	case ast.LIST_ITEM:
+		if entering {
+			result = r.cr(result)
+			result = appendStr(result, "<li>")
+		} else {
+			result = appendStr(result, "</li>\n")
+		}
+	case ast.CODE_BLOCK:
+		if !entering {
+			break
+		}
+		result = r.cr(result)
+		result = appendStr(result, "<pre><code")
+		if len(node.Info) > 0 {
+			result = appendStr(result, " class=\"language-")
+			result = r.appendEscaped(result, renderer.InfoWord(node.Info))
+			result = appendStr(result, "\"")
+		}
+		result = appendStr(result, ">")
+		result = r.appendEscaped(result, node.Literal)
+		result = appendStr(result, "</code></pre>\n")
+	case ast.HORIZON:
+		if entering {
+			result = r.cr(result)
+			result = appendStr(result, "<hr"+r.voidEnd()+"\n")
+		}
+	case ast.TABLE:
+		if entering {
+			result = r.cr(result)
+			result = appendStr(result, "<table>\n<thead>\n")
+			break
+		}
+		if len(node.Children) > 1 {
+			result = appendStr(result, "</tbody>\n")
+		}
+		result = appendStr(result, "</table>\n")
+	case ast.TABLE_ROW:
+		if entering {
+			result = appendStr(result, "<tr>\n")
+			break
+		}
+		result = appendStr(result, "</tr>\n")
+		if table := node.Parent; table != nil && table.Children[0] == node {
+			result = appendStr(result, "</thead>\n")
+			if len(table.Children) > 1 {
+				result = appendStr(result, "<tbody>\n")
+			}
+		}
+	case ast.TABLE_CELL:
+		tag := "td"
+		if row := node.Parent; row != nil && row.Parent != nil && row.Parent.Children[0] == row {
+			tag = "th"
+		}
+		if !entering {
+			result = appendStr(result, "</"+tag+">\n")
+			break
+		}
+		result = appendStr(result, "<"+tag)
+		if node.Align != ast.ALIGN_NONE && r.xhtml {
+			result = appendStr(result, " style=\"text-align: "+string(node.Align)+"\"")
+		} else if node.Align != ast.ALIGN_NONE {
+			result = appendStr(result, " align=\""+string(node.Align)+"\"")
+		}
+		result = appendStr(result, ">")
+	case ast.TEXT:
+		if entering {
+			result = r.appendEscaped(result, node.Literal)
+		}
+	case ast.SOFT_BREAK:
+		if entering {
+			result = appendStr(result, "\n")
+		}
+	case ast.CODE:
+		if entering {
+			result = appendStr(result, "<code>")
+			result = r.appendEscaped(result, node.Literal)
+			result = appendStr(result, "</code>")
+		}
+	case ast.EMPHASIS:
+		if entering {
+			result = appendStr(result, "<em>")
+		} else {
+			result = appendStr(result, "</em>")
+		}
+	case ast.STRONG:
+		if entering {
+			result = appendStr(result, "<strong>")
+		} else {
+			result = appendStr(result, "</strong>")
+		}
+	case ast.LINK:
+		if !entering {
+			result = appendStr(result, "</a>")
+			break
+		}
+		result = appendStr(result, "<a href=\"")
+		result = r.appendEscaped(result, safeDestination(node.Destination, false))
+		result = appendStr(result, "\"")
+		if len(node.Title) > 0 {
+			result = appendStr(result, " title=\"")
+			result = r.appendEscaped(result, node.Title)
+			result = appendStr(result, "\"")
+		}
+		result = appendStr(result, ">")
+	case ast.IMAGE:
+		if !entering {
+			break
+		}
+		result = appendStr(result, "<img src=\"")
+		result = r.appendEscaped(result, safeDestination(node.Destination, true))
+		result = appendStr(result, "\" alt=\"")
+		result = r.appendEscaped(result, renderer.PlainText(nil, node))
+		result = appendStr(result, "\"")
+		if len(node.Title) > 0 {
+			result = appendStr(result, " title=\"")
+			result = r.appendEscaped(result, node.Title)
+			result = appendStr(result, "\"")
+		}
+		result = appendStr(result, r.voidEnd())
+		// the description is already written as the alt text
+		status = renderer.SkipChildren
+	default:
+		return renderer.Terminate, fmt.Errorf("unsupported node type: %q", node.Type)
+	}
+
+	if len(result) == 0 {
+		return status, nil
+	}
+	r.last = result[len(result)-1]
+	_, err := w.Write(result)
+	return status, err
+}
+
+// cr starts a new line unless nothing was written yet or the output already ends with one.
+func (r *Renderer) cr(result []byte) []byte {
+	last := r.last
+	if len(result) > 0 {
+		last = result[len(result)-1]
+	}
+	if last != 0 && last != '\n' {
+		result = append(result, '\n')
+	}
+	return result
+}
+
+// voidEnd returns the end of the start tag of a void element.
+func (r *Renderer) voidEnd() string {
+	if r.xhtml {
+		return " />"
+	}
+	return ">"
+}
+
+// safeDestination returns dest, or nothing if its scheme can run script or read local files:
+// javascript:, vbscript:, file: and data:, except data:image/ for images.
+func safeDestination(dest []byte, image bool) []byte {
+	// browsers skip control characters and spaces in a scheme, as in "java\tscript:"
+	colon := bytes.IndexByte(dest, ':')
+	if colon < 0 {
+		return dest
+	}
+	var scheme []byte
+	for _, ch := range dest[:colon] {
+		if ch <= ' ' || ch == 0x7f {
+			continue
+		}
+		if !('a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || ch == '+' || ch == '-' || ch == '.') {
+			// a relative path, like "a/b:c"
+			return dest
+		}
+		scheme = append(scheme, ch)
+	}
+	switch string(bytes.ToLower(scheme)) {
+	case "javascript", "vbscript", "file":
+		return nil
+	case "data":
+		rest := bytes.TrimLeft(dest[colon+1:], " \t\n")
+		if image && len(rest) >= len("image/") && bytes.EqualFold(rest[:len("image/")], []byte("image/")) {
+			return dest
+		}
+		return nil
+	}
+	return dest
+}
+
+// appendEscaped appends chs to slice, escaping the characters that are special in html,
+// and in XHTML also replacing the characters that XML doesn't allow.
+func (r *Renderer) appendEscaped(slice []byte, chs []byte) []byte {
+	if r.xhtml {
+		return AppendXMLEscaped(slice, chs)
+	}
+	return AppendEscaped(slice, chs)
+}
+
+// AppendXMLEscaped is AppendEscaped for XML: the characters that XML doesn't allow,
+// such as most control characters, and bytes that are not UTF-8 are replaced with U+FFFD.
+func AppendXMLEscaped(slice []byte, chs []byte) []byte {
+	return AppendEscaped(slice, ValidXML(chs))
+}
+
+// ValidXML returns chs with the characters that XML doesn't allow replaced with U+FFFD.
+// It returns chs itself when they are all allowed.
+func ValidXML(chs []byte) []byte {
+	for i := 0; i < len(chs); {
+		r, size := utf8.DecodeRune(chs[i:])
+		if !allowedInXML(r, size) {
+			return bytes.Map(func(r rune) rune {
+				if !allowedInXML(r, utf8.RuneLen(r)) {
+					return utf8.RuneError
+				}
+				return r
+			}, chs)
+		}
+		i += size
+	}
+	return chs
+}
+
+// allowedInXML reports whether the rune r of size bytes is a Char of XML 1.0.
+func allowedInXML(r rune, size int) bool {
+	switch {
+	case r == utf8.RuneError:
+		// U+FFFD itself is allowed, a byte that is not UTF-8 is not
+		return size > 1
+	case r == '\t' || r == '\n' || r == '\r':
+		return true
+	case r < 0x20:
+		return false
+	case r <= 0xD7FF:
+		return true
+	case r < 0xE000:
+		return false
+	case r <= 0xFFFD:
+		return true
+	}
+	return r >= 0x10000 && r <= utf8.MaxRune
+}
+
+// AppendEscaped appends chs to slice, escaping the characters that are special in html.
+func AppendEscaped(slice []byte, chs []byte) []byte {
+	for _, ch := range chs {
+		switch ch {
+		case '&':
+			slice = appendStr(slice, "&amp;")
+		case '<':
+			slice = appendStr(slice, "&lt;")
+		case '>':
+			slice = appendStr(slice, "&gt;")
+		case '"':
+			slice = appendStr(slice, "&quot;")
+		default:
+			slice = append(slice, ch)
+		}
+	}
+	return slice
+}
+
+func appendStr(slice []byte, str string) []byte {
+	return append(slice, str...)
+}
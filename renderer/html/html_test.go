@@ -0,0 +1,117 @@
+package html
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+)
+
+type errorWriter struct{}
+
+func (errorWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write error")
+}
+
+func TestRenderNode(t *testing.T) {
+	doc := ast.NewNode(ast.DOCUMENT)
+	list := ast.NewNode(ast.LIST)
+	list.Ordered = true
+	list.Start = 3
+	doc.AppendChild(list)
+	item := ast.NewNode(ast.LIST_ITEM)
+	list.AppendChild(item)
+	image := ast.NewNode(ast.IMAGE)
+	image.Destination = []byte("logo.png")
+	image.Title = []byte("\"Logo\"")
+	item.AppendChild(image)
+	text := ast.NewNode(ast.TEXT)
+	text.Literal = []byte("a & b")
+	image.AppendChild(text)
+
+	var got bytes.Buffer
+	if err := renderer.Render(&got, NewRenderer(), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "<ol start=\"3\">\n<li><img src=\"logo.png\" alt=\"a &amp; b\" title=\"&quot;Logo&quot;\"></li>\n</ol>\n"
+	if got.String() != want {
+		t.Errorf("html wrong.\nexpected=%q\ngot=%q", want, got.String())
+	}
+}
+
+func TestRenderNodeError(t *testing.T) {
+	doc := ast.NewNode(ast.DOCUMENT)
+	doc.AppendChild(ast.NewNode(ast.HORIZON))
+
+	if err := renderer.Render(errorWriter{}, NewRenderer(), doc); err == nil {
+		t.Error("error is not returned")
+	}
+	if err := renderer.Render(&bytes.Buffer{}, NewRenderer(), ast.NewNode("UNKNOWN")); err == nil {
+		t.Error("error is not returned for an unknown node")
+	}
+}
+
+func TestRenderNodeXHTML(t *testing.T) {
+	doc := ast.NewNode(ast.DOCUMENT)
+	doc.AppendChild(ast.NewNode(ast.HORIZON))
+	paragraph := ast.NewNode(ast.PARAGRAPH)
+	doc.AppendChild(paragraph)
+	image := ast.NewNode(ast.IMAGE)
+	image.Destination = []byte("logo.png")
+	paragraph.AppendChild(image)
+	table := ast.NewNode(ast.TABLE)
+	doc.AppendChild(table)
+	row := ast.NewNode(ast.TABLE_ROW)
+	table.AppendChild(row)
+	cell := ast.NewNode(ast.TABLE_CELL)
+	cell.Align = ast.ALIGN_CENTER
+	row.AppendChild(cell)
+
+	var got bytes.Buffer
+	if err := renderer.Render(&got, NewXHTMLRenderer(), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "<hr />\n<p><img src=\"logo.png\" alt=\"\" /></p>\n<table>\n<thead>\n<tr>\n<th style=\"text-align: center\"></th>\n</tr>\n</thead>\n</table>\n"
+	if got.String() != want {
+		t.Errorf("xhtml wrong.\nexpected=%q\ngot=%q", want, got.String())
+	}
+}
+
+func TestRenderNodeUnsafeDestination(t *testing.T) {
+	tests := []struct {
+		typ         ast.NodeType
+		destination string
+		expected    string
+	}{
+		{ast.LINK, "https://example.com/", `<a href="https://example.com/"></a>`},
+		{ast.LINK, "docs/a:b.md", `<a href="docs/a:b.md"></a>`},
+		{ast.LINK, "data", `<a href="data"></a>`},
+		{ast.LINK, "javascript:alert(1)", `<a href=""></a>`},
+		{ast.LINK, "JavaScript:alert(1)", `<a href=""></a>`},
+		{ast.LINK, " java\tscript:alert(1)", `<a href=""></a>`},
+		{ast.LINK, "vbscript:msgbox(1)", `<a href=""></a>`},
+		{ast.LINK, "file:///etc/passwd", `<a href=""></a>`},
+		{ast.LINK, "data:text/html,<script>alert(1)</script>", `<a href=""></a>`},
+		{ast.LINK, "data:image/png;base64,AAAA", `<a href=""></a>`},
+		{ast.IMAGE, "data:image/png;base64,AAAA", `<img src="data:image/png;base64,AAAA" alt="">`},
+		{ast.IMAGE, "data:text/html,x", `<img src="" alt="">`},
+		{ast.IMAGE, "javascript:alert(1)", `<img src="" alt="">`},
+	}
+
+	for i, tt := range tests {
+		node := ast.NewNode(tt.typ)
+		node.Destination = []byte(tt.destination)
+
+		var got bytes.Buffer
+		if err := renderer.Render(&got, NewRenderer(), node); err != nil {
+			t.Fatalf("tests[%d] - %v", i, err)
+		}
+		if got.String() != tt.expected {
+			t.Errorf("tests[%d] - html wrong. expected=%q, got=%q", i, tt.expected, got.String())
+		}
+	}
+}
@@ -0,0 +1,316 @@
+// Package jira renders a document tree as the wiki markup of Jira.
+package jira
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+)
+
+// languages are the languages of the code macro, by the names of renderer.CodeLanguage.
+// Code blocks in other languages are written without one.
+var languages = map[string]bool{
+	"bash":       true,
+	"c":          true,
+	"cpp":        true,
+	"csharp":     true,
+	"css":        true,
+	"go":         true,
+	"groovy":     true,
+	"html":       true,
+	"java":       true,
+	"javascript": true,
+	"json":       true,
+	"kotlin":     true,
+	"php":        true,
+	"python":     true,
+	"ruby":       true,
+	"scala":      true,
+	"sql":        true,
+	"swift":      true,
+	"typescript": true,
+	"xml":        true,
+	"yaml":       true,
+}
+
+// Renderer renders a document tree as Jira wiki markup.
+// A list item in Jira is a single line, so the paragraphs of an item are joined with line breaks,
+// and its other blocks are written after the list with a warning. Quotes can't be nested and
+// columns can't be aligned, which is warned about as well.
+// It keeps whether a block was written, and the warnings.
+type Renderer struct {
+	// started is true once a block was written.
+	started  bool
+	warnings []renderer.Warning
+}
+
+// NewRenderer initializes Renderer.
+func NewRenderer() *Renderer {
+	return &Renderer{}
+}
+
+// Warnings implements renderer.Warner.
+func (r *Renderer) Warnings() []renderer.Warning {
+	return r.warnings
+}
+
+// RenderNode implements renderer.Renderer. Each block is written as a whole when it is entered.
+func (r *Renderer) RenderNode(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+	if node.Type == ast.DOCUMENT || !entering {
+		return renderer.GoToNext, nil
+	}
+
+	var result []byte
+	if r.started {
+		result = append(result, '\n')
+	}
+	r.started = true
+	result, err := r.appendBlock(result, node)
+	if err != nil {
+		return renderer.Terminate, err
+	}
+	_, err = w.Write(result)
+	return renderer.SkipChildren, err
+}
+
+// appendBlocks writes blocks separated by a blank line.
+func (r *Renderer) appendBlocks(result []byte, blocks []*ast.Node) ([]byte, error) {
+	for i, block := range blocks {
+		if i > 0 {
+			result = append(result, '\n')
+		}
+		var err error
+		if result, err = r.appendBlock(result, block); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// appendBlock writes a block; the result always ends with a line feed.
+func (r *Renderer) appendBlock(result []byte, node *ast.Node) ([]byte, error) {
+	var err error
+	switch node.Type {
+	case ast.HEADING:
+		result = appendStr(result, "h"+strconv.Itoa(node.Level)+". ")
+		if result, err = r.appendInlines(result, node); err != nil {
+			return nil, err
+		}
+		result = append(result, '\n')
+	case ast.PARAGRAPH:
+		start := len(result)
+		if result, err = r.appendInlines(result, node); err != nil {
+			return nil, err
+		}
+		// a paragraph starting like a list item would be one
+		if len(result) > start && (result[start] == '-' || result[start] == '#') {
+			result = append(result[:start], append([]byte{'\\'}, result[start:]...)...)
+		}
+		result = append(result, '\n')
+	case ast.BLOCK_QUOTE:
+		for parent := node.Parent; parent != nil; parent = parent.Parent {
+			if parent.Type == ast.BLOCK_QUOTE {
+				r.warn(node, "nested quote written as part of the outer one")
+				return r.appendBlocks(result, node.Children)
+			}
+		}
+		result = appendStr(result, "{quote}\n")
+		if result, err = r.appendBlocks(result, node.Children); err != nil {
+			return nil, err
+		}
+		result = appendStr(result, "{quote}\n")
+	case ast.LIST:
+		var after []*ast.Node
+		if result, after, err = r.appendList(result, node, ""); err != nil {
+			return nil, err
+		}
+		for _, block := range after {
+			result = append(result, '\n')
+			if result, err = r.appendBlock(result, block); err != nil {
+				return nil, err
+			}
+		}
+	case ast.CODE_BLOCK:
+		result = appendStr(result, "{code")
+		if language := renderer.CodeLanguage(node.Info); languages[language] {
+			result = appendStr(result, ":"+language)
+		}
+		result = appendStr(result, "}\n")
+		result = append(result, node.Literal...)
+		if len(node.Literal) > 0 && node.Literal[len(node.Literal)-1] != '\n' {
+			result = append(result, '\n')
+		}
+		result = appendStr(result, "{code}\n")
+	case ast.HORIZON:
+		result = appendStr(result, "----\n")
+	case ast.TABLE:
+		if result, err = r.appendTable(result, node); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported node type: %q", node.Type)
+	}
+	return result, nil
+}
+
+// appendList writes the items of list on lines starting with the markers of the lists they are in, like `#*`.
+// It returns the blocks of the items that can't be written on their lines, to be written after the list.
+func (r *Renderer) appendList(result []byte, list *ast.Node, markers string) ([]byte, []*ast.Node, error) {
+	marker := "*"
+	if list.Ordered {
+		marker = "#"
+	}
+	markers += marker
+	if list.Ordered && list.Start != 1 {
+		r.warn(list, "list numbered from 1 instead of "+strconv.Itoa(list.Start))
+	}
+
+	var after []*ast.Node
+	for _, item := range list.Children {
+		result = appendStr(result, markers+" ")
+		var nested []*ast.Node
+		paragraphs := 0
+		for _, block := range item.Children {
+			switch block.Type {
+			case ast.PARAGRAPH:
+				if paragraphs > 0 {
+					result = appendStr(result, " \\\\ ")
+				}
+				var err error
+				if result, err = r.appendInlines(result, block); err != nil {
+					return nil, nil, err
+				}
+				paragraphs++
+			case ast.LIST:
+				nested = append(nested, block)
+			default:
+				r.warn(block, "block in a list item written after the list")
+				after = append(after, block)
+			}
+		}
+		result = append(result, '\n')
+
+		for _, list := range nested {
+			var more []*ast.Node
+			var err error
+			if result, more, err = r.appendList(result, list, markers); err != nil {
+				return nil, nil, err
+			}
+			after = append(after, more...)
+		}
+	}
+	return result, after, nil
+}
+
+// appendTable writes a table with the header cells between `||` and the others between `|`.
+func (r *Renderer) appendTable(result []byte, table *ast.Node) ([]byte, error) {
+	aligned := false
+	for i, row := range table.Children {
+		separator := "|"
+		if i == 0 {
+			separator = "||"
+		}
+		for _, cell := range row.Children {
+			aligned = aligned || cell.Align != ast.ALIGN_NONE
+			result = appendStr(result, separator)
+			start := len(result)
+			var err error
+			if result, err = r.appendInlines(result, cell); err != nil {
+				return nil, err
+			}
+			// an empty cell would join the separators
+			if len(result) == start {
+				result = append(result, ' ')
+			}
+		}
+		result = appendStr(result, separator+"\n")
+	}
+	if aligned {
+		r.warn(table, "alignment of table columns left out")
+	}
+	return result, nil
+}
+
+func (r *Renderer) appendInlines(result []byte, node *ast.Node) ([]byte, error) {
+	for _, child := range node.Children {
+		var err error
+		switch child.Type {
+		case ast.TEXT:
+			result = appendEscaped(result, child.Literal)
+		case ast.SOFT_BREAK:
+			// a line feed would be a line break
+			result = append(result, ' ')
+		case ast.CODE:
+			result = appendStr(result, "{{")
+			result = appendEscaped(result, child.Literal)
+			result = appendStr(result, "}}")
+		case ast.EMPHASIS, ast.STRONG:
+			delimiter := "_"
+			if child.Type == ast.STRONG {
+				delimiter = "*"
+			}
+			result = appendStr(result, delimiter)
+			if result, err = r.appendInlines(result, child); err != nil {
+				return nil, err
+			}
+			result = appendStr(result, delimiter)
+		case ast.LINK:
+			result = appendStr(result, "[")
+			if text := renderer.PlainText(nil, child); len(text) > 0 && !bytes.Equal(text, child.Destination) {
+				result = appendEscaped(result, text)
+				result = appendStr(result, "|")
+			}
+			result = appendDestination(result, child.Destination)
+			result = appendStr(result, "]")
+		case ast.IMAGE:
+			if !bytes.Contains(child.Destination, []byte("://")) {
+				r.warn(child, "image "+strconv.Quote(string(child.Destination))+" has to be attached to the issue")
+			}
+			result = appendStr(result, "!")
+			result = appendDestination(result, child.Destination)
+			result = appendStr(result, "!")
+		default:
+			return nil, fmt.Errorf("unsupported node type: %q", child.Type)
+		}
+	}
+	return result, nil
+}
+
+func (r *Renderer) warn(node *ast.Node, message string) {
+	r.warnings = append(r.warnings, renderer.Warning{Pos: node.Pos, Message: message})
+}
+
+// appendEscaped appends chs to slice, escaping the characters that start or end the markup of Jira.
+func appendEscaped(slice []byte, chs []byte) []byte {
+	for _, ch := range chs {
+		switch ch {
+		case '\\', '*', '_', '{', '}', '[', ']', '|', '!', '^', '~', '+', '-', '?':
+			slice = append(slice, '\\', ch)
+		default:
+			slice = append(slice, ch)
+		}
+	}
+	return slice
+}
+
+// appendDestination appends the destination of a link or an image to slice, percent-encoding the characters
+// that end the markup around it, which can't be escaped there.
+func appendDestination(slice []byte, destination []byte) []byte {
+	for _, ch := range destination {
+		switch ch {
+		case '[', ']', '|', '!':
+			slice = append(slice, '%', "0123456789ABCDEF"[ch>>4], "0123456789ABCDEF"[ch&0xf])
+		default:
+			slice = append(slice, ch)
+		}
+	}
+	return slice
+}
+
+func appendStr(slice []byte, str string) []byte {
+	return append(slice, str...)
+}
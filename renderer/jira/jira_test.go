@@ -0,0 +1,70 @@
+package jira
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+type expected struct {
+	input string
+	want  string
+}
+
+func compareGotAndWant(t *testing.T, tests []expected) {
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		if err := parser.New(lexer.New([]byte(tt.input))).RenderTo(&buf, NewRenderer()); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("tests[%d] - wiki markup wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []expected{
+		{input: "# Heading1\n### *Heading3*\nDescription1\nDescription2\n", want: "h1. Heading1\n\nh3. _Heading3_\n\nDescription1 Description2\n"},
+		{input: "*a* **b** `c{d}` e[f]|g!\n", want: "_a_ *b* {{c\\{d\\}}} e\\[f\\]\\|g\\!\n"},
+		{input: "[a](https://example.com) [https://example.com](https://example.com) ![b](https://example.com/b.png)\n", want: "[a|https://example.com] [https://example.com] !https://example.com/b.png!\n"},
+		{input: "[a](<https://example.com/a]|b>) ![c](<https://example.com/c!d e.png>)\n", want: "[a|https://example.com/a%5D%7Cb] !https://example.com/c%21d e.png!\n"},
+		{input: "> a\n", want: "{quote}\na\n{quote}\n"},
+		{input: "- List1\n- List2\n  1. List2_1\n", want: "* List1\n* List2\n*# List2\\_1\n"},
+		{input: "- a\n\n  b\n", want: "* a \\\\ b\n"},
+		{input: "- a\n\n  ```\n  b\n  ```\n", want: "* a\n\n{code}\nb\n{code}\n"},
+		{input: "```go\n*a*\n```\n```unknown\nb\n```\n", want: "{code:go}\n*a*\n{code}\n\n{code}\nb\n{code}\n"},
+		{input: "***\n", want: "----\n"},
+		{input: "| a | b |\n| --- | --- |\n| c |  |\n", want: "||a||b||\n|c| |\n"},
+		{input: "#1 issue\n", want: "\\#1 issue\n"},
+		{input: "-deleted- ??cite?? +under+ ^sup^ ~sub~\n", want: "\\-deleted\\- \\?\\?cite\\?\\? \\+under\\+ \\^sup\\^ \\~sub\\~\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestWarnings(t *testing.T) {
+	r := NewRenderer()
+	input := "2. a\n\n   > b\n\n> > c\n\n| d |\n| :-: |\n\n![e](e.png)\n"
+	if err := parser.New(lexer.New([]byte(input))).RenderTo(&bytes.Buffer{}, r); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, warning := range r.Warnings() {
+		got = append(got, warning.String())
+	}
+	want := []string{
+		"1:1: list numbered from 1 instead of 2",
+		"3:4: block in a list item written after the list",
+		"5:3: nested quote written as part of the outer one",
+		"7:1: alignment of table columns left out",
+		"10:1: image \"e.png\" has to be attached to the issue",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings wrong.\nexpected=%q\ngot=%q", want, got)
+	}
+}
@@ -0,0 +1,316 @@
+// Package latex renders a document tree as LaTeX.
+package latex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+)
+
+// Options configures the LaTeX written by Renderer.
+type Options struct {
+	// Standalone writes a complete document with a preamble that loads the packages the output needs,
+	// instead of a body to be included in another document.
+	Standalone bool
+}
+
+// Preamble starts a standalone document. A document including the body needs the same packages:
+// hyperref for links, graphicx for images, listings for code blocks in a known language
+// and alltt for code blocks that contain the end of their environment.
+const Preamble = `\documentclass{article}
+\usepackage[utf8]{inputenc}
+\usepackage[T1]{fontenc}
+\usepackage{hyperref}
+\usepackage{graphicx}
+\usepackage{listings}
+\usepackage{alltt}
+\begin{document}
+`
+
+// languages maps the languages of renderer.CodeLanguage to the names known to the listings package.
+// Code blocks in other languages are written in a verbatim environment.
+var languages = map[string]string{
+	"c":       "C",
+	"cpp":     "C++",
+	"java":    "Java",
+	"python":  "Python",
+	"ruby":    "Ruby",
+	"perl":    "Perl",
+	"php":     "PHP",
+	"sql":     "SQL",
+	"html":    "HTML",
+	"xml":     "XML",
+	"bash":    "bash",
+	"tex":     "TeX",
+	"latex":   "TeX",
+	"haskell": "Haskell",
+	"lisp":    "Lisp",
+	"matlab":  "Matlab",
+	"fortran": "Fortran",
+	"pascal":  "Pascal",
+}
+
+// sections are the commands of the heading levels.
+var sections = []string{"section", "subsection", "subsubsection", "paragraph", "subparagraph", "subparagraph"}
+
+// counters are the counters of the nesting levels of enumerate.
+var counters = []string{"enumi", "enumii", "enumiii", "enumiv"}
+
+// Renderer renders a document tree as LaTeX: headings as sections, lists as itemize and enumerate,
+// quotes as quote, code blocks as lstlisting, verbatim or alltt, and tables as tabular.
+// It keeps whether the next block is separated by a blank line.
+type Renderer struct {
+	opts Options
+
+	// blank is true when the next block is separated by a blank line.
+	blank bool
+}
+
+// NewRenderer initializes Renderer.
+func NewRenderer(opts Options) *Renderer {
+	return &Renderer{opts: opts}
+}
+
+// RenderNode implements renderer.Renderer.
+func (r *Renderer) RenderNode(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+	var result []byte
+	status := renderer.GoToNext
+
+	switch node.Type {
+	case ast.DOCUMENT:
+		if !r.opts.Standalone {
+			break
+		}
+		if entering {
+			result = appendStr(result, Preamble)
+		} else {
+			result = appendStr(result, "\\end{document}\n")
+		}
+	case ast.HEADING:
+		if entering {
+			result = r.startBlock(result)
+			result = appendStr(result, "\\"+sections[node.Level-1]+"{")
+		} else {
+			result = appendStr(result, "}\n")
+			r.blank = true
+		}
+	case ast.PARAGRAPH:
+		if entering {
+			result = r.startBlock(result)
+		} else {
+			result = appendStr(result, "\n")
+			r.blank = true
+		}
+	case ast.BLOCK_QUOTE:
+		result = r.environment(result, "quote", entering)
+	case ast.LIST:
+		name := "itemize"
+		if node.Ordered {
+			name = "enumerate"
+		}
+		result = r.environment(result, name, entering)
+		if entering && node.Ordered && node.Start != 1 {
+			if depth := orderedDepth(node); depth < len(counters) {
+				result = appendStr(result, "\\setcounter{"+counters[depth]+"}{"+strconv.Itoa(node.Start-1)+"}\n")
+			}
+		}
+	case ast.LIST_ITEM:
+		if entering {
+			result = appendStr(result, "\\item ")
+			r.blank = false
+		} else if len(node.Children) == 0 {
+			result = appendStr(result, "\n")
+		}
+	case ast.CODE_BLOCK:
+		if !entering {
+			break
+		}
+		result = r.startBlock(result)
+		name, option := "verbatim", ""
+		if language, ok := languages[renderer.CodeLanguage(node.Info)]; ok {
+			name, option = "lstlisting", "[language="+language+"]"
+		}
+		literal := node.Literal
+		if bytes.Contains(literal, []byte("\\end{"+name+"}")) {
+			// the code would end the environment, so it is escaped in one where commands work
+			name, option = "alltt", ""
+			literal = AppendEscaped(nil, literal)
+		}
+		result = appendStr(result, "\\begin{"+name+"}"+option+"\n")
+		result = append(result, literal...)
+		if len(literal) > 0 && literal[len(literal)-1] != '\n' {
+			result = append(result, '\n')
+		}
+		result = appendStr(result, "\\end{"+name+"}\n")
+		r.blank = true
+	case ast.HORIZON:
+		if entering {
+			result = r.startBlock(result)
+			result = appendStr(result, "\\noindent\\rule{\\linewidth}{0.4pt}\n")
+			r.blank = true
+		}
+	case ast.TABLE:
+		if !entering {
+			result = appendStr(result, "\\end{tabular}\n")
+			r.blank = true
+			break
+		}
+		result = r.startBlock(result)
+		result = appendStr(result, "\\begin{tabular}{")
+		if len(node.Children) > 0 {
+			for _, cell := range node.Children[0].Children {
+				result = appendStr(result, columnSpec(cell.Align))
+			}
+		}
+		result = appendStr(result, "}\n")
+	case ast.TABLE_ROW:
+		if entering {
+			break
+		}
+		result = appendStr(result, " \\\\\n")
+		if table := node.Parent; table != nil && table.Children[0] == node {
+			result = appendStr(result, "\\hline\n")
+		}
+	case ast.TABLE_CELL:
+		if entering && node.Parent != nil && node.Parent.Children[0] != node {
+			result = appendStr(result, " & ")
+		}
+	case ast.TEXT:
+		if entering {
+			result = AppendEscaped(result, node.Literal)
+		}
+	case ast.SOFT_BREAK:
+		if entering {
+			result = appendStr(result, "\n")
+		}
+	case ast.CODE:
+		if entering {
+			result = appendStr(result, "\\texttt{")
+			result = AppendEscaped(result, node.Literal)
+			result = appendStr(result, "}")
+		}
+	case ast.EMPHASIS:
+		result = command(result, "emph", entering)
+	case ast.STRONG:
+		result = command(result, "textbf", entering)
+	case ast.LINK:
+		if !entering {
+			result = appendStr(result, "}")
+			break
+		}
+		result = appendStr(result, "\\href{")
+		result = AppendURL(result, node.Destination)
+		result = appendStr(result, "}{")
+	case ast.IMAGE:
+		if entering {
+			result = appendStr(result, "\\includegraphics{")
+			result = AppendURL(result, node.Destination)
+			result = appendStr(result, "}")
+		}
+		// an image has no room for its description
+		status = renderer.SkipChildren
+	default:
+		return renderer.Terminate, fmt.Errorf("unsupported node type: %q", node.Type)
+	}
+
+	if len(result) == 0 {
+		return status, nil
+	}
+	_, err := w.Write(result)
+	return status, err
+}
+
+// startBlock separates a block from the previous one with a blank line.
+func (r *Renderer) startBlock(result []byte) []byte {
+	if r.blank {
+		result = append(result, '\n')
+	}
+	r.blank = false
+	return result
+}
+
+// environment begins or ends the environment name around the children of a block.
+func (r *Renderer) environment(result []byte, name string, entering bool) []byte {
+	if entering {
+		result = r.startBlock(result)
+		return appendStr(result, "\\begin{"+name+"}\n")
+	}
+	r.blank = true
+	return appendStr(result, "\\end{"+name+"}\n")
+}
+
+// command opens or closes the command name around the children of an inline.
+func command(result []byte, name string, entering bool) []byte {
+	if entering {
+		return appendStr(result, "\\"+name+"{")
+	}
+	return appendStr(result, "}")
+}
+
+// orderedDepth returns the number of ordered lists list is nested in.
+func orderedDepth(list *ast.Node) int {
+	depth := 0
+	for parent := list.Parent; parent != nil; parent = parent.Parent {
+		if parent.Type == ast.LIST && parent.Ordered {
+			depth++
+		}
+	}
+	return depth
+}
+
+// columnSpec returns the column specifier of tabular for an alignment.
+func columnSpec(align ast.Alignment) string {
+	switch align {
+	case ast.ALIGN_CENTER:
+		return "c"
+	case ast.ALIGN_RIGHT:
+		return "r"
+	default:
+		return "l"
+	}
+}
+
+// AppendEscaped appends chs to slice, escaping the characters that are special in LaTeX.
+func AppendEscaped(slice []byte, chs []byte) []byte {
+	for _, ch := range chs {
+		switch ch {
+		case '\\':
+			slice = appendStr(slice, "\\textbackslash{}")
+		case '~':
+			slice = appendStr(slice, "\\textasciitilde{}")
+		case '^':
+			slice = appendStr(slice, "\\textasciicircum{}")
+		case '<':
+			slice = appendStr(slice, "\\textless{}")
+		case '>':
+			slice = appendStr(slice, "\\textgreater{}")
+		case '&', '%', '$', '#', '_', '{', '}':
+			slice = append(slice, '\\', ch)
+		default:
+			slice = append(slice, ch)
+		}
+	}
+	return slice
+}
+
+// AppendURL appends the url chs to slice for the argument of \href or \includegraphics,
+// escaping the characters that would end the argument or start a comment.
+func AppendURL(slice []byte, chs []byte) []byte {
+	for _, ch := range chs {
+		switch ch {
+		case '\\', '#', '%', '{', '}':
+			slice = append(slice, '\\', ch)
+		default:
+			slice = append(slice, ch)
+		}
+	}
+	return slice
+}
+
+func appendStr(slice []byte, str string) []byte {
+	return append(slice, str...)
+}
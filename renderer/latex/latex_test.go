@@ -0,0 +1,63 @@
+package latex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+	"github.com/istsh/markdown-viewer/renderer"
+)
+
+type expected struct {
+	input string
+	want  string
+}
+
+func compareGotAndWant(t *testing.T, tests []expected) {
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		if err := parser.New(lexer.New([]byte(tt.input))).RenderTo(&buf, NewRenderer(Options{})); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("tests[%d] - latex wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []expected{
+		{input: "# Heading1\n## Heading2\nDescription1\nDescription2\n", want: "\\section{Heading1}\n\n\\subsection{Heading2}\n\nDescription1\nDescription2\n"},
+		{input: "*a* **b** `c_d` [e](f#g)\n", want: "\\emph{a} \\textbf{b} \\texttt{c\\_d} \\href{f\\#g}{e}\n"},
+		{input: "![a](b.png)\n", want: "\\includegraphics{b.png}\n"},
+		{input: "\\ ~ ^ < > & % $ # _ { }\n", want: "\\textbackslash{} \\textasciitilde{} \\textasciicircum{} \\textless{} \\textgreater{} \\& \\% \\$ \\# \\_ \\{ \\}\n"},
+		{input: "> a\n", want: "\\begin{quote}\na\n\\end{quote}\n"},
+		{input: "- List1\n- List2\n  - List2_1\n", want: "\\begin{itemize}\n\\item List1\n\\item List2\n\n\\begin{itemize}\n\\item List2\\_1\n\\end{itemize}\n\\end{itemize}\n"},
+		{input: "3. List1\n   1. List1_1\n   2. List1_2\n", want: "\\begin{enumerate}\n\\setcounter{enumi}{2}\n\\item List1\n\n\\begin{enumerate}\n\\item List1\\_1\n\\item List1\\_2\n\\end{enumerate}\n\\end{enumerate}\n"},
+		{input: "```python\nprint(\"\\\\\")\n```\n", want: "\\begin{lstlisting}[language=Python]\nprint(\"\\\\\")\n\\end{lstlisting}\n"},
+		{input: "```go\n_ = 1\n```\n", want: "\\begin{verbatim}\n_ = 1\n\\end{verbatim}\n"},
+		{input: "```C++ title\nint a;\n```\n", want: "\\begin{lstlisting}[language=C++]\nint a;\n\\end{lstlisting}\n"},
+		// code that would end its environment is escaped
+		{input: "```\n\\end{verbatim}\n\\input{x}\n```\n", want: "\\begin{alltt}\n\\textbackslash{}end\\{verbatim\\}\n\\textbackslash{}input\\{x\\}\n\\end{alltt}\n"},
+		{input: "```python\n\\end{lstlisting} # a\n```\n", want: "\\begin{alltt}\n\\textbackslash{}end\\{lstlisting\\} \\# a\n\\end{alltt}\n"},
+		{input: "***\n", want: "\\noindent\\rule{\\linewidth}{0.4pt}\n"},
+		{input: "| a | b | c |\n| :-: | --: | --- |\n| d & e |\n", want: "\\begin{tabular}{crl}\na & b & c \\\\\n\\hline\nd \\& e &  &  \\\\\n\\end{tabular}\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestRenderStandalone(t *testing.T) {
+	doc := parser.New(lexer.New([]byte("a\n"))).ParseDocument()
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, NewRenderer(Options{Standalone: true}), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	got := buf.String()
+	if !strings.HasPrefix(got, Preamble) || !strings.HasSuffix(got, "a\n\\end{document}\n") {
+		t.Errorf("document wrong.\ngot=%q", got)
+	}
+}
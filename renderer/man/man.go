@@ -0,0 +1,286 @@
+// Package man renders a document tree as a man page in the roff language of man(7).
+package man
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/text"
+)
+
+// titlePattern matches the first heading of a man page, like `markdown-viewer(1) -- render markdown`.
+var titlePattern = regexp.MustCompile(`^(\S+)\(([0-9][a-z]*)\)(?:\s+--?\s+(.*))?$`)
+
+// Renderer renders a document tree as a man page.
+// The first first-level heading is the title of the page, given as `name(section)` and optionally followed by ` -- description`
+// for the NAME section. Other first-level and second-level headings start sections, and deeper ones subsections.
+// Emphasis is written in italics, and strong emphasis and code in bold.
+// It keeps the last byte it wrote, to start requests on a new line and to escape the start of a line,
+// and whether it wrote the title.
+type Renderer struct {
+	// last is the last byte written, used to start requests on a new line and to escape the start of a line.
+	last byte
+	// titled is true once the title was written, which a page has only one of.
+	titled bool
+}
+
+// NewRenderer initializes Renderer.
+func NewRenderer() *Renderer {
+	return &Renderer{}
+}
+
+// RenderNode implements renderer.Renderer.
+func (r *Renderer) RenderNode(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+	var result []byte
+	status := renderer.GoToNext
+
+	switch node.Type {
+	case ast.DOCUMENT:
+	case ast.HEADING:
+		if !entering {
+			break
+		}
+		result = r.cr(result)
+		title := strings.TrimSpace(string(renderer.PlainText(nil, node)))
+		switch {
+		case node.Level == 1 && !r.titled:
+			result = appendTitle(result, title)
+			r.titled = true
+		case node.Level <= 2:
+			result = appendRequest(result, ".SH", strings.ToUpper(title))
+		default:
+			result = appendRequest(result, ".SS", title)
+		}
+		status = renderer.SkipChildren
+	case ast.PARAGRAPH:
+		if entering {
+			result = r.cr(result)
+			result = appendParagraph(result, node)
+		} else {
+			result = appendStr(result, "\n")
+		}
+	case ast.BLOCK_QUOTE, ast.LIST:
+		result = r.cr(result)
+		if node.Type == ast.LIST && !nested(node) {
+			break
+		}
+		// quotes and nested lists are indented
+		if entering {
+			result = appendStr(result, ".RS 4\n")
+		} else {
+			result = appendStr(result, ".RE\n")
+		}
+	case ast.LIST_ITEM:
+		if !entering {
+			break
+		}
+		result = r.cr(result)
+		list := node.Parent
+		if list == nil || !list.Ordered {
+			result = appendStr(result, ".IP \\(bu 2\n")
+			break
+		}
+		number := list.Start
+		for _, item := range list.Children {
+			if item == node {
+				break
+			}
+			number++
+		}
+		result = appendStr(result, ".IP \""+strconv.Itoa(number)+string(list.Delimiter)+"\" 4\n")
+	case ast.CODE_BLOCK:
+		if !entering {
+			break
+		}
+		result = r.cr(result)
+		result = appendParagraph(result, node)
+		result = appendStr(result, ".RS 4\n.nf\n")
+		result = appendLines(result, node.Literal)
+		result = appendStr(result, ".fi\n.RE\n")
+	case ast.HORIZON:
+		if entering {
+			result = r.cr(result)
+			result = appendStr(result, ".PP\n\\l'\\n(.lu'\n")
+		}
+	case ast.TABLE:
+		if !entering {
+			break
+		}
+		result = r.cr(result)
+		result = appendParagraph(result, node)
+		result = appendStr(result, ".RS 4\n.nf\n")
+		result = appendLines(result, text.AppendTable(nil, node))
+		result = appendStr(result, ".fi\n.RE\n")
+		status = renderer.SkipChildren
+	case ast.TEXT:
+		if entering {
+			result = r.appendEscaped(result, node.Literal)
+		}
+	case ast.SOFT_BREAK:
+		if entering {
+			result = appendStr(result, "\n")
+		}
+	case ast.CODE:
+		if entering {
+			result = appendStr(result, "\\fB")
+			result = r.appendEscaped(result, node.Literal)
+			result = appendStr(result, "\\f"+font(node.Parent))
+		}
+	case ast.EMPHASIS, ast.STRONG:
+		if entering {
+			result = appendStr(result, "\\f"+font(node))
+		} else {
+			result = appendStr(result, "\\f"+font(node.Parent))
+		}
+	case ast.LINK:
+		if entering {
+			break
+		}
+		// the destination follows the text, unless it is the text itself
+		if destination := node.Destination; !bytes.Equal(destination, renderer.PlainText(nil, node)) {
+			result = appendStr(result, " <")
+			result = r.appendEscaped(result, destination)
+			result = appendStr(result, ">")
+		}
+	case ast.IMAGE:
+		if entering {
+			result = r.appendEscaped(result, renderer.PlainText(nil, node))
+		}
+		status = renderer.SkipChildren
+	default:
+		return renderer.Terminate, fmt.Errorf("unsupported node type: %q", node.Type)
+	}
+
+	if len(result) == 0 {
+		return status, nil
+	}
+	r.last = result[len(result)-1]
+	_, err := w.Write(result)
+	return status, err
+}
+
+// cr starts a new line unless nothing was written yet or the output already ends with one.
+func (r *Renderer) cr(result []byte) []byte {
+	last := r.last
+	if len(result) > 0 {
+		last = result[len(result)-1]
+	}
+	if last != 0 && last != '\n' {
+		result = append(result, '\n')
+	}
+	return result
+}
+
+// appendEscaped appends the text chs to slice, escaping backslashes and hyphens,
+// and the dots and quotes at the start of a line that would be read as requests.
+func (r *Renderer) appendEscaped(slice []byte, chs []byte) []byte {
+	for _, ch := range chs {
+		last := r.last
+		if len(slice) > 0 {
+			last = slice[len(slice)-1]
+		}
+		switch {
+		case ch == '\\':
+			slice = appendStr(slice, "\\e")
+		case ch == '-':
+			slice = appendStr(slice, "\\-")
+		case (ch == '.' || ch == '\'') && (last == 0 || last == '\n'):
+			slice = append(slice, '\\', '&', ch)
+		default:
+			slice = append(slice, ch)
+		}
+	}
+	return slice
+}
+
+// appendTitle appends the .TH request for the title of the page,
+// and the NAME section if the title has a description.
+func appendTitle(slice []byte, title string) []byte {
+	name, section, description := title, "1", ""
+	if m := titlePattern.FindStringSubmatch(title); m != nil {
+		name, section, description = m[1], m[2], m[3]
+	}
+
+	slice = appendRequest(slice, ".TH", strings.ToUpper(name), section)
+	if description != "" {
+		slice = appendStr(slice, ".SH NAME\n")
+		slice = appendLines(slice, []byte(name+" - "+description))
+	}
+	return slice
+}
+
+// appendRequest appends a request with its arguments quoted.
+func appendRequest(slice []byte, request string, args ...string) []byte {
+	slice = appendStr(slice, request)
+	for _, arg := range args {
+		slice = appendStr(slice, " \"")
+		slice = appendStr(slice, strings.NewReplacer("\\", "\\e", "\"", "\\(dq").Replace(arg))
+		slice = append(slice, '"')
+	}
+	return append(slice, '\n')
+}
+
+// appendLines appends lines of text as they are, such as the content of a code block.
+func appendLines(slice []byte, chs []byte) []byte {
+	lines := (&Renderer{}).appendEscaped(nil, chs)
+	if len(lines) > 0 && lines[len(lines)-1] != '\n' {
+		lines = append(lines, '\n')
+	}
+	return append(slice, lines...)
+}
+
+// font returns the font of the text in node: B for strong emphasis, I for emphasis, BI for both, and R otherwise.
+func font(node *ast.Node) string {
+	var bold, italic bool
+	for ; node != nil; node = node.Parent {
+		switch node.Type {
+		case ast.STRONG:
+			bold = true
+		case ast.EMPHASIS:
+			italic = true
+		}
+	}
+	switch {
+	case bold && italic:
+		return "(BI"
+	case bold:
+		return "B"
+	case italic:
+		return "I"
+	default:
+		return "R"
+	}
+}
+
+// appendParagraph starts a new paragraph for the block node, keeping the indent of the list item it is in.
+// The first block of an item follows the tag of .IP.
+func appendParagraph(slice []byte, node *ast.Node) []byte {
+	parent := node.Parent
+	if parent == nil || parent.Type != ast.LIST_ITEM {
+		return appendStr(slice, ".PP\n")
+	}
+	if parent.Children[0] == node {
+		return slice
+	}
+	return appendStr(slice, ".IP\n")
+}
+
+// nested reports whether list is in another list.
+func nested(list *ast.Node) bool {
+	for parent := list.Parent; parent != nil; parent = parent.Parent {
+		if parent.Type == ast.LIST {
+			return true
+		}
+	}
+	return false
+}
+
+func appendStr(slice []byte, str string) []byte {
+	return append(slice, str...)
+}
@@ -0,0 +1,45 @@
+package man
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+type expected struct {
+	input string
+	want  string
+}
+
+func compareGotAndWant(t *testing.T, tests []expected) {
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		if err := parser.New(lexer.New([]byte(tt.input))).RenderTo(&buf, NewRenderer()); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("tests[%d] - man wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []expected{
+		{input: "# tool(8) -- do things\n## Synopsis\nDescription1\nDescription2\n### Sub\n", want: ".TH \"TOOL\" \"8\"\n.SH NAME\ntool \\- do things\n.SH \"SYNOPSIS\"\n.PP\nDescription1\nDescription2\n.SS \"Sub\"\n"},
+		{input: "# tool\n", want: ".TH \"TOOL\" \"1\"\n"},
+		// a page has one title
+		{input: "# tool\n# Second h1\n", want: ".TH \"TOOL\" \"1\"\n.SH \"SECOND H1\"\n"},
+		{input: "*a* **b** `-c` **d *e* f** [g](h)\n", want: ".PP\n\\fIa\\fR \\fBb\\fR \\fB\\-c\\fR \\fBd \\f(BIe\\fB f\\fR g <h>\n"},
+		{input: "a\\b\n.c\n'd\n", want: ".PP\na\\eb\n\\&.c\n\\&'d\n"},
+		{input: "> a\n", want: ".RS 4\n.PP\na\n.RE\n"},
+		{input: "- List1\n- List2\n  - List2_1\n", want: ".IP \\(bu 2\nList1\n.IP \\(bu 2\nList2\n.RS 4\n.IP \\(bu 2\nList2_1\n.RE\n"},
+		{input: "3. List1\n4. List2\n", want: ".IP \"3.\" 4\nList1\n.IP \"4.\" 4\nList2\n"},
+		{input: "- a\n\n  b\n", want: ".IP \\(bu 2\na\n.IP\nb\n"},
+		{input: "```sh\n.x -y\n```\n", want: ".PP\n.RS 4\n.nf\n\\&.x \\-y\n.fi\n.RE\n"},
+		{input: "| a | b |\n| --- | --- |\n| cc | d |\n", want: ".PP\n.RS 4\n.nf\na   b\n\\-\\-  \\-\ncc  d\n.fi\n.RE\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
@@ -0,0 +1,542 @@
+// Package markdown writes a document tree back as markdown text.
+package markdown
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer/text"
+)
+
+// Options configures the markdown text written by Render.
+type Options struct {
+	// LineEnding ends every line. It is "\n" if empty.
+	LineEnding string
+
+	// PreserveLineEnding ends every line with the line ending of the source document instead of LineEnding.
+	PreserveLineEnding bool
+
+	// Width reflows paragraphs to lines of at most Width columns, counting the markers of quotes and lists.
+	// A word longer than a line, or one that would start a new block at the start of a line, makes the line longer.
+	// Paragraphs keep their line breaks if it is 0.
+	Width int
+}
+
+// Render writes doc as markdown text.
+// Lists are written with `-` or their numbers, headings with `#`, and code blocks with fences.
+func Render(doc *ast.Node, opts Options) []byte {
+	result := renderBlocks(nil, doc.Children, true, opts.Width)
+
+	lineEnding := opts.LineEnding
+	if opts.PreserveLineEnding && len(doc.LineEnding) > 0 {
+		lineEnding = string(doc.LineEnding)
+	}
+	if lineEnding != "" && lineEnding != "\n" {
+		result = bytes.Replace(result, []byte("\n"), []byte(lineEnding), -1)
+	}
+	return result
+}
+
+// renderBlocks writes blocks one after another, separated by a blank line unless tight.
+// width is the number of columns left for paragraphs, or 0 to keep their line breaks.
+func renderBlocks(result []byte, blocks []*ast.Node, separate bool, width int) []byte {
+	for i, block := range blocks {
+		if i > 0 && separate {
+			result = append(result, '\n')
+		}
+		result = renderBlock(result, block, width)
+	}
+	return result
+}
+
+// renderBlock writes a block; the result always ends with a line feed.
+func renderBlock(result []byte, node *ast.Node, width int) []byte {
+	switch node.Type {
+	case ast.HEADING:
+		result = appendStr(result, strings.Repeat("#", node.Level)+" ")
+		result = renderInlines(result, node)
+		result = append(result, '\n')
+	case ast.PARAGRAPH:
+		result = appendWrapped(result, node, width)
+	case ast.BLOCK_QUOTE:
+		inner := renderBlocks(nil, node.Children, true, innerWidth(width, 2))
+		if len(inner) == 0 {
+			inner = []byte{'\n'}
+		}
+		result = text.AppendPrefixed(result, inner, "> ", "> ")
+	case ast.LIST:
+		for i, item := range node.Children {
+			if i > 0 && !node.Tight {
+				result = append(result, '\n')
+			}
+			marker := "- "
+			if node.Ordered {
+				marker = strconv.Itoa(node.Start+i) + string(node.Delimiter) + " "
+			}
+			inner := renderBlocks(nil, item.Children, !node.Tight, innerWidth(width, len(marker)))
+			if len(inner) == 0 {
+				inner = []byte{'\n'}
+			}
+			result = text.AppendPrefixed(result, inner, marker, strings.Repeat(" ", len(marker)))
+		}
+	case ast.CODE_BLOCK:
+		// an info string with a backtick needs a fence of tildes
+		fenceCh := byte('`')
+		if bytes.IndexByte(node.Info, '`') >= 0 {
+			fenceCh = '~'
+		}
+		fence := strings.Repeat(string(fenceCh), maxRun(node.Literal, fenceCh)+1)
+		if len(fence) < 3 {
+			fence = strings.Repeat(string(fenceCh), 3)
+		}
+		result = appendStr(result, fence)
+		if len(node.Info) > 0 && node.Info[0] == fenceCh {
+			// the info string would lengthen the fence
+			result = append(result, ' ')
+		}
+		result = append(result, node.Info...)
+		result = append(result, '\n')
+		result = append(result, node.Literal...)
+		result = appendStr(result, fence+"\n")
+	case ast.HORIZON:
+		result = appendStr(result, "---\n")
+	case ast.TABLE:
+		result = appendTable(result, node)
+	}
+	return result
+}
+
+// innerWidth returns the width left for the content of a container whose marker takes markerWidth columns.
+func innerWidth(width int, markerWidth int) int {
+	if width == 0 {
+		return 0
+	}
+	if width-markerWidth < 1 {
+		return 1
+	}
+	return width - markerWidth
+}
+
+// appendWrapped writes the inlines of a paragraph reflowed to lines of at most width columns,
+// or with the line breaks of the source if width is 0.
+// A line is never broken before a word that would start a new block.
+// The first word keeps the line break of the source after it if it would start a block with text after it, like `#`,
+// and its line otherwise if it would start a block alone, like `---`.
+// A first word that would open a code fence keeps its line up to the next backtick, which makes it text.
+// A line break of the source before a "*" or "+" indented less than 4 columns is kept with its indentation,
+// since other dialects of markdown start a list there where this one continues the paragraph.
+func appendWrapped(result []byte, node *ast.Node, width int) []byte {
+	w := &words{column: node.Pos.Column}
+	w.addInlines(node)
+	w.space()
+
+	// fence is true while the first line starts with a fence without a backtick after it
+	fence := len(w.list) > 0 && opensFence(w.list[0])
+	lineWidth := 0
+	for i, word := range w.list {
+		wordWidth := text.Width(string(word))
+		if i > 0 {
+			lineBreak := w.lineBreaks[i] && !startsBlock(word)
+			if width > 0 {
+				lineBreak = lineWidth+1+wordWidth > width && !startsBlock(word)
+			}
+			if i == 1 && startsBlock(w.list[0]) {
+				lineBreak = w.lineBreaks[1] && opensBlock(w.list[0])
+			}
+			keep := w.lineBreaks[i] && w.indents[i] < 4 && isOtherBullet(word)
+			if keep {
+				lineBreak = true
+			}
+			if fence {
+				lineBreak = false
+				fence = !bytes.Contains(word, []byte("`"))
+			}
+
+			switch {
+			case keep:
+				result = append(result, '\n')
+				result = appendStr(result, strings.Repeat(" ", w.indents[i]))
+				lineWidth = w.indents[i]
+			case lineBreak && startsBlock(word):
+				// an indented line continues the paragraph whatever it starts with
+				result = appendStr(result, "\n    ")
+				lineWidth = 4
+			case lineBreak:
+				result = append(result, '\n')
+				lineWidth = 0
+			default:
+				result = append(result, ' ')
+				lineWidth++
+			}
+		}
+		result = append(result, word...)
+		lineWidth += wordWidth
+	}
+	return append(result, '\n')
+}
+
+// words splits the inlines of a paragraph at the places a line can be broken:
+// the spaces in text and the line breaks. Code spans and link destinations are never broken.
+type words struct {
+	list    [][]byte
+	current []byte
+
+	// lineBreaks tells whether each word of list follows a line break.
+	lineBreaks []bool
+	lineBreak  bool
+
+	// indents are the columns each word of list following a line break is indented by in the source,
+	// from column, the column the paragraph starts at.
+	indents []int
+	column  int
+	indent  int
+}
+
+func (w *words) addInlines(node *ast.Node) {
+	for _, child := range node.Children {
+		switch child.Type {
+		case ast.TEXT:
+			if w.lineBreak && len(w.current) == 0 && child.Pos.Column > w.column {
+				w.indent = child.Pos.Column - w.column
+			}
+			for i, text := range splitSpaces(child.Literal) {
+				if i > 0 {
+					w.space()
+				}
+				w.current = append(w.current, text...)
+			}
+		case ast.SOFT_BREAK:
+			w.space()
+			w.lineBreak = true
+		case ast.EMPHASIS, ast.STRONG:
+			delimiter := emphasisDelimiter(child)
+			w.current = appendStr(w.current, delimiter)
+			w.addInlines(child)
+			w.current = appendStr(w.current, delimiter)
+		case ast.LINK, ast.IMAGE:
+			if child.Type == ast.IMAGE {
+				w.current = append(w.current, '!')
+			}
+			w.current = append(w.current, '[')
+			w.addInlines(child)
+			w.current = appendLinkEnd(w.current, child)
+		default:
+			w.current = renderInline(w.current, child)
+		}
+	}
+}
+
+// splitSpaces splits text at each space and tab, like bytes.Split.
+func splitSpaces(text []byte) [][]byte {
+	var pieces [][]byte
+	for {
+		i := bytes.IndexAny(text, " \t")
+		if i < 0 {
+			return append(pieces, text)
+		}
+		pieces = append(pieces, text[:i])
+		text = text[i+1:]
+	}
+}
+
+// space ends the current word.
+func (w *words) space() {
+	if len(w.current) > 0 {
+		w.list = append(w.list, w.current)
+		w.lineBreaks = append(w.lineBreaks, w.lineBreak)
+		w.indents = append(w.indents, w.indent)
+		w.current = nil
+		w.lineBreak = false
+		w.indent = 0
+	}
+}
+
+// startsBlock reports whether a line starting with word would start a new block instead of continuing a paragraph,
+// such as a heading, a list item, a quote, a fence, a thematic break or a delimiter row of a table.
+func startsBlock(word []byte) bool {
+	if len(bytes.Trim(word, "#-*_=+:|~`")) == 0 || word[0] == '>' ||
+		bytes.HasPrefix(word, []byte("```")) || bytes.HasPrefix(word, []byte("~~~")) {
+		return true
+	}
+
+	// an ordered list marker
+	digits := len(word) - len(bytes.TrimLeft(word, "0123456789"))
+	return digits > 0 && digits <= 9 && len(word) == digits+1 && (word[digits] == '.' || word[digits] == ')')
+}
+
+// opensBlock reports whether a line starting with word followed by text would start a new block,
+// such as a heading, a list item, a quote or a fence.
+func opensBlock(word []byte) bool {
+	if len(word) <= 6 && len(bytes.Trim(word, "#")) == 0 {
+		return true
+	}
+	switch string(word) {
+	case "-", "+", "*":
+		return true
+	}
+	if word[0] == '>' || bytes.HasPrefix(word, []byte("```")) || bytes.HasPrefix(word, []byte("~~~")) {
+		return true
+	}
+
+	// an ordered list marker
+	digits := len(word) - len(bytes.TrimLeft(word, "0123456789"))
+	return digits > 0 && digits <= 9 && len(word) == digits+1 && (word[digits] == '.' || word[digits] == ')')
+}
+
+// opensFence reports whether a line starting with word would open a fenced code block
+// unless a backtick follows it on the line: a run of three or more backticks without another backtick after it.
+func opensFence(word []byte) bool {
+	run := len(word) - len(bytes.TrimLeft(word, "`"))
+	return run >= 3 && !bytes.Contains(word[run:], []byte("`"))
+}
+
+// isOtherBullet reports whether word is a bullet that starts a list in CommonMark
+// but continues a paragraph in this dialect.
+func isOtherBullet(word []byte) bool {
+	return string(word) == "*" || string(word) == "+"
+}
+
+// appendTable writes a table with its columns padded to the same width, and the pipes in cells escaped.
+func appendTable(result []byte, table *ast.Node) []byte {
+	var rows [][]string
+	var widths []int
+	for _, row := range table.Children {
+		var cells []string
+		for i, cell := range row.Children {
+			content := strings.ReplaceAll(string(renderInlines(nil, cell)), "|", "\\|")
+			cells = append(cells, content)
+			if i >= len(widths) {
+				// a delimiter needs at least 3 characters
+				widths = append(widths, 3)
+			}
+			if width := text.Width(content); width > widths[i] {
+				widths[i] = width
+			}
+		}
+		rows = append(rows, cells)
+	}
+	if len(rows) == 0 {
+		return result
+	}
+	alignments := make([]ast.Alignment, len(widths))
+	for i, cell := range table.Children[0].Children {
+		alignments[i] = cell.Align
+	}
+
+	appendRow := func(cells []string) {
+		result = append(result, '|')
+		for i, cell := range cells {
+			padding := widths[i] - text.Width(cell)
+			left, right := 0, padding
+			switch alignments[i] {
+			case ast.ALIGN_RIGHT:
+				left, right = padding, 0
+			case ast.ALIGN_CENTER:
+				left, right = padding/2, padding-padding/2
+			}
+			result = appendStr(result, " "+strings.Repeat(" ", left)+cell+strings.Repeat(" ", right)+" |")
+		}
+		result = append(result, '\n')
+	}
+
+	appendRow(rows[0])
+	var delimiters []string
+	for i, width := range widths {
+		switch alignments[i] {
+		case ast.ALIGN_LEFT:
+			delimiters = append(delimiters, ":"+strings.Repeat("-", width-1))
+		case ast.ALIGN_RIGHT:
+			delimiters = append(delimiters, strings.Repeat("-", width-1)+":")
+		case ast.ALIGN_CENTER:
+			delimiters = append(delimiters, ":"+strings.Repeat("-", width-2)+":")
+		default:
+			delimiters = append(delimiters, strings.Repeat("-", width))
+		}
+	}
+	appendRow(delimiters)
+	for _, cells := range rows[1:] {
+		appendRow(cells)
+	}
+	return result
+}
+
+func renderInlines(result []byte, node *ast.Node) []byte {
+	for _, child := range node.Children {
+		result = renderInline(result, child)
+	}
+	return result
+}
+
+func renderInline(result []byte, node *ast.Node) []byte {
+	switch node.Type {
+	case ast.TEXT:
+		result = append(result, node.Literal...)
+	case ast.SOFT_BREAK:
+		result = append(result, '\n')
+	case ast.CODE:
+		fence := strings.Repeat("`", codeFenceLength(node))
+		result = appendStr(result, fence)
+		// a space keeps a backtick at either end from joining the fence,
+		// and keeps the spaces at both ends from being stripped, unless the code span has only whitespace
+		padding := len(node.Literal) > 0 && (node.Literal[0] == '`' || node.Literal[len(node.Literal)-1] == '`' ||
+			(node.Literal[0] == ' ' && node.Literal[len(node.Literal)-1] == ' ' && len(bytes.TrimSpace(node.Literal)) > 0))
+		// a space also keeps the code span from being read as the destination of a link after `](`
+		padding = padding || (len(bytes.TrimSpace(node.Literal)) > 0 && followsLinkText(node))
+		if padding {
+			result = append(result, ' ')
+		}
+		result = append(result, node.Literal...)
+		if padding {
+			result = append(result, ' ')
+		}
+		result = appendStr(result, fence)
+	case ast.EMPHASIS, ast.STRONG:
+		delimiter := emphasisDelimiter(node)
+		result = appendStr(result, delimiter)
+		result = renderInlines(result, node)
+		result = appendStr(result, delimiter)
+	case ast.LINK, ast.IMAGE:
+		if node.Type == ast.IMAGE {
+			result = append(result, '!')
+		}
+		result = append(result, '[')
+		result = renderInlines(result, node)
+		result = appendLinkEnd(result, node)
+	}
+	return result
+}
+
+// emphasisDelimiter returns the delimiter of EMPHASIS or STRONG, which is `*`.
+// `_` of the source is kept when the block has another delimiter character in its text or a `*` delimiter,
+// since the `*` could pair with them differently or join their run.
+func emphasisDelimiter(node *ast.Node) string {
+	ch := "*"
+	if node.Delimiter == '_' && hasDelimiters(node) {
+		ch = "_"
+	}
+	if node.Type == ast.STRONG {
+		return ch + ch
+	}
+	return ch
+}
+
+// hasDelimiters reports whether the block containing node has `*` or `_` in its text, or `*` as a delimiter.
+func hasDelimiters(node *ast.Node) bool {
+	for node.Parent != nil && !node.IsBlock() {
+		node = node.Parent
+	}
+
+	var found bool
+	var walk func(*ast.Node)
+	walk = func(n *ast.Node) {
+		switch n.Type {
+		case ast.TEXT:
+			found = found || bytes.IndexAny(n.Literal, "*_") >= 0
+		case ast.EMPHASIS, ast.STRONG:
+			found = found || n.Delimiter == '*'
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(node)
+	return found
+}
+
+// appendLinkEnd writes the end of a link or an image after its text: the destination and the title.
+func appendLinkEnd(result []byte, node *ast.Node) []byte {
+	result = appendStr(result, "](")
+	result = appendDestination(result, node.Destination)
+	if len(node.Title) > 0 {
+		result = appendStr(result, " \"")
+		result = appendEscaped(result, node.Title, "\"\\")
+		result = append(result, '"')
+	}
+	return append(result, ')')
+}
+
+// appendDestination writes a link destination, enclosing it in `<>` if it contains spaces or parentheses.
+func appendDestination(result []byte, destination []byte) []byte {
+	if len(destination) > 0 && bytes.IndexAny(destination, " ()<>") < 0 {
+		return appendEscaped(result, destination, "\\")
+	}
+	result = append(result, '<')
+	result = appendEscaped(result, destination, "<>\\")
+	return append(result, '>')
+}
+
+// appendEscaped writes chs with a backslash before each of special.
+func appendEscaped(result []byte, chs []byte, special string) []byte {
+	for _, ch := range chs {
+		if strings.IndexByte(special, ch) >= 0 {
+			result = append(result, '\\')
+		}
+		result = append(result, ch)
+	}
+	return result
+}
+
+// followsLinkText reports whether the text before node ends with `](`.
+func followsLinkText(node *ast.Node) bool {
+	if node.Parent == nil {
+		return false
+	}
+	var prev *ast.Node
+	for _, child := range node.Parent.Children {
+		if child == node {
+			break
+		}
+		prev = child
+	}
+	return prev != nil && prev.Type == ast.TEXT && bytes.HasSuffix(prev.Literal, []byte("]("))
+}
+
+// codeFenceLength returns the length of the run of backticks around the code span node:
+// longer than any run in its content, and of no run in the text of its block, which would close it early.
+func codeFenceLength(node *ast.Node) int {
+	block := node
+	for block.Parent != nil && !block.IsBlock() {
+		block = block.Parent
+	}
+	runs := map[int]bool{}
+	var walk func(*ast.Node)
+	walk = func(n *ast.Node) {
+		if n.Type == ast.TEXT {
+			for _, run := range bytes.FieldsFunc(n.Literal, func(r rune) bool { return r != '`' }) {
+				runs[len(run)] = true
+			}
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(block)
+
+	length := maxRun(node.Literal, '`') + 1
+	for runs[length] {
+		length++
+	}
+	return length
+}
+
+// maxRun returns the length of the longest run of ch in chs.
+func maxRun(chs []byte, ch byte) int {
+	longest, run := 0, 0
+	for _, c := range chs {
+		if c != ch {
+			run = 0
+			continue
+		}
+		run++
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+func appendStr(slice []byte, str string) []byte {
+	return append(slice, str...)
+}
@@ -0,0 +1,143 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+type expected struct {
+	input string
+	opts  Options
+	want  string
+}
+
+func compareGotAndWant(t *testing.T, tests []expected) {
+	for i, tt := range tests {
+		doc := parser.New(lexer.New([]byte(tt.input))).ParseDocument()
+		got := Render(doc, tt.opts)
+		if string(got) != tt.want {
+			t.Errorf("tests[%d] - markdown wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []expected{
+		{input: "# Heading1\nDescription1\nDescription2\n", want: "# Heading1\n\nDescription1\nDescription2\n"},
+		{input: "> # Heading1\n> Description1\n>\n> > Description2\n", want: "> # Heading1\n>\n> Description1\n>\n> > Description2\n"},
+		{input: "- List1\n- List2\n\t- List2-1\n", want: "- List1\n- List2\n  - List2-1\n"},
+		{input: "- List1\n\n  Description1\n- List2\n", want: "- List1\n\n  Description1\n\n- List2\n"},
+		{input: "3) List1\n4) List2\n   - List2_1\n", want: "3) List1\n4) List2\n   - List2_1\n"},
+		{input: "~~~go\nfmt.Println(\"```\")\n~~~\n", want: "````go\nfmt.Println(\"```\")\n````\n"},
+		{input: "    code\n", want: "```\ncode\n```\n"},
+		{input: "***\n", want: "---\n"},
+		{input: "_italic_ __bold__ `` a`b ``\n", want: "*italic* **bold** ``a`b``\n"},
+		{input: "[a](b \"Title\") ![c](<d e>)\n", want: "[a](b \"Title\") ![c](<d e>)\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestRenderLineEnding(t *testing.T) {
+	tests := []expected{
+		{input: "# Heading1\r\nDescription1\r\n", want: "# Heading1\n\nDescription1\n"},
+		{input: "# Heading1\r\nDescription1\r\n", opts: Options{PreserveLineEnding: true}, want: "# Heading1\r\n\r\nDescription1\r\n"},
+		{input: "# Heading1\rDescription1\r", opts: Options{PreserveLineEnding: true}, want: "# Heading1\r\rDescription1\r"},
+		{input: "# Heading1\nDescription1\n", opts: Options{LineEnding: "\r\n"}, want: "# Heading1\r\n\r\nDescription1\r\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestRenderWidth(t *testing.T) {
+	tests := []expected{
+		{input: "aaa bbb\nccc ddd eee\n", opts: Options{Width: 8}, want: "aaa bbb\nccc ddd\neee\n"},
+		{input: "aaa bbb ccc\n", opts: Options{Width: 80}, want: "aaa bbb ccc\n"},
+		{input: "aaa\nbbb\n", opts: Options{Width: 80}, want: "aaa bbb\n"},
+		// a word longer than the width has a line of its own
+		{input: "a bbbbbbbbbb c\n", opts: Options{Width: 4}, want: "a\nbbbbbbbbbb\nc\n"},
+		// the markers of containers take a part of the width
+		{input: "> aaa bbb ccc\n", opts: Options{Width: 9}, want: "> aaa bbb\n> ccc\n"},
+		{input: "1. aaa bbb ccc\n", opts: Options{Width: 10}, want: "1. aaa bbb\n   ccc\n"},
+		// code spans and link destinations are never broken
+		{input: "a `b c` [d e](f)\n", opts: Options{Width: 1}, want: "a\n`b c`\n[d\ne](f)\n"},
+		// a word that would start a block is never moved to the start of a line
+		{input: "aaa - bbb # ccc 1. ddd\n", opts: Options{Width: 3}, want: "aaa -\nbbb #\nccc 1.\nddd\n"},
+		{input: "aaa\n    - bbb\n", want: "aaa - bbb\n"},
+		{input: "#\nbbb ccc\n", opts: Options{Width: 80}, want: "#\nbbb ccc\n"},
+		// wide characters take two columns
+		{input: "日本 語\n", opts: Options{Width: 5}, want: "日本\n語\n"},
+		// a fence at the start of a line is text only with a backtick after it
+		{input: "``` starts a fence, but not when a backtick such as ` follows it.\n", opts: Options{Width: 20}, want: "``` starts a fence, but not when a backtick such as `\nfollows it.\n"},
+		// a bullet of CommonMark keeps its line
+		{input: "* a\n* b\n  + c\n", opts: Options{Width: 80}, want: "* a\n* b\n  + c\n"},
+		{input: "a\n    * b\n", opts: Options{Width: 80}, want: "a * b\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestRenderTable(t *testing.T) {
+	tests := []expected{
+		{
+			input: "a|b\n-|:-:\nccc|d\n",
+			want:  "| a   |  b  |\n| --- | :-: |\n| ccc |  d  |\n",
+		},
+		{
+			input: "| a | b |\n| :-- | --: |\n| c \\| d |\n| e |\n",
+			want:  "| a      |   b |\n| :----- | --: |\n| c \\| d |     |\n| e      |     |\n",
+		},
+		{
+			input: "| 日本 | a |\n| - | - |\n",
+			want:  "| 日本 | a   |\n| ---- | --- |\n",
+		},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+// TestRenderGolden checks that formatting the example documents and the inputs that used to change
+// keeps their html, and that the result is already formatted.
+func TestRenderGolden(t *testing.T) {
+	paths, err := filepath.Glob("../../testdata/*.md.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := map[string][]byte{
+		// reflowing used to start a line with the fence
+		"fence":   []byte("``` starts a fence, but not when a backtick such as ` follows it on the line.\n\nafter\n"),
+		"tilde":   []byte("a ``` b ~~~ c\n\nafter\n"),
+		"bullets": []byte("* a\n* b\n+ c\n\n- d\n  e\n    - f\n"),
+	}
+	for _, path := range paths {
+		input, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[path] = input
+	}
+	// line breaks in text and blanks between the tags don't change the meaning
+	blanks := regexp.MustCompile(`\s+`)
+	tags := regexp.MustCompile(`>\s<`)
+	html := func(input []byte) string {
+		return tags.ReplaceAllString(blanks.ReplaceAllString(string(parser.New(lexer.New(input)).Parse()), " "), "><")
+	}
+
+	for name, input := range inputs {
+		for _, width := range []int{0, 1, 20, 40, 80} {
+			opts := Options{Width: width}
+			got := Render(parser.New(lexer.New(input)).ParseDocument(), opts)
+			if html(got) != html(input) {
+				t.Errorf("%s width %d - html changed.\nexpected=%s\ngot=%s", name, width, html(input), html(got))
+			}
+			if again := Render(parser.New(lexer.New(got)).ParseDocument(), opts); string(again) != string(got) {
+				t.Errorf("%s width %d - not formatted.\nexpected=%q\ngot=%q", name, width, got, again)
+			}
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Package mdast converts a document tree to the mdast format of unified,
+// so that it can be used by remark and its plugins.
+// See https://github.com/syntax-tree/mdast.
+package mdast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/token"
+)
+
+// Node is a node of mdast. Only the fields used by its type are set.
+type Node struct {
+	Type     string   `json:"type"`
+	Children []*Node  `json:"-"`
+	Position Position `json:"position"`
+
+	// Value of text, inlineCode and code.
+	Value *string `json:"value,omitempty"`
+
+	// Depth of heading.
+	Depth int `json:"depth,omitempty"`
+
+	// Ordered, Start and Spread of list; Spread of listItem.
+	Ordered *bool `json:"ordered,omitempty"`
+	Start   *int  `json:"start,omitempty"`
+	Spread  *bool `json:"spread,omitempty"`
+
+	// Lang and Meta of code.
+	Lang *string `json:"lang,omitempty"`
+	Meta *string `json:"meta,omitempty"`
+
+	// Align of table, the alignment of each column: "left", "center", "right" or null.
+	Align []*string `json:"align,omitempty"`
+
+	// URL, Title and Alt of link and image.
+	URL   *string `json:"url,omitempty"`
+	Title *string `json:"title,omitempty"`
+	Alt   *string `json:"alt,omitempty"`
+}
+
+// Position is where a node starts and ends in the source, as in unist:
+// columns and offsets count the UTF-16 code units of the source, as JavaScript indexes strings.
+type Position struct {
+	Start token.Position `json:"start"`
+	End   token.Position `json:"end"`
+}
+
+// Convert returns the mdast tree of node and its descendants, parsed from source.
+// The source is needed for the positions, which count UTF-16 code units rather than the bytes and characters
+// of the positions of node.
+func Convert(node *ast.Node, source []byte) (*Node, error) {
+	n, err := convert(node)
+	if err != nil {
+		return nil, err
+	}
+	newUTF16Positions(source).convert(n)
+	return n, nil
+}
+
+func convert(node *ast.Node) (*Node, error) {
+	n := &Node{Position: Position{Start: node.Pos, End: node.End}}
+
+	switch node.Type {
+	case ast.DOCUMENT:
+		n.Type = "root"
+	case ast.HEADING:
+		n.Type = "heading"
+		n.Depth = node.Level
+	case ast.PARAGRAPH:
+		n.Type = "paragraph"
+	case ast.BLOCK_QUOTE:
+		n.Type = "blockquote"
+	case ast.LIST:
+		n.Type = "list"
+		n.Ordered = boolPtr(node.Ordered)
+		if node.Ordered {
+			n.Start = &node.Start
+		}
+		n.Spread = boolPtr(!node.Tight)
+	case ast.LIST_ITEM:
+		n.Type = "listItem"
+		n.Spread = boolPtr(spread(node.Children))
+	case ast.CODE_BLOCK:
+		n.Type = "code"
+		// the value doesn't end with a line ending
+		n.Value = stringPtr(string(bytes.TrimSuffix(node.Literal, []byte("\n"))))
+		if info := bytes.TrimSpace(node.Info); len(info) > 0 {
+			lang, meta := info, []byte(nil)
+			if i := bytes.IndexAny(info, " \t"); i >= 0 {
+				lang, meta = info[:i], bytes.TrimSpace(info[i:])
+			}
+			n.Lang = stringPtr(string(lang))
+			if len(meta) > 0 {
+				n.Meta = stringPtr(string(meta))
+			}
+		}
+		return n, nil
+	case ast.HORIZON:
+		n.Type = "thematicBreak"
+		return n, nil
+	case ast.TABLE:
+		n.Type = "table"
+		n.Align = []*string{}
+		if len(node.Children) > 0 {
+			for _, cell := range node.Children[0].Children {
+				var align *string
+				if cell.Align != ast.ALIGN_NONE {
+					align = stringPtr(string(cell.Align))
+				}
+				n.Align = append(n.Align, align)
+			}
+		}
+	case ast.TABLE_ROW:
+		n.Type = "tableRow"
+	case ast.TABLE_CELL:
+		n.Type = "tableCell"
+	case ast.TEXT:
+		n.Type = "text"
+		n.Value = stringPtr(string(node.Literal))
+		return n, nil
+	case ast.SOFT_BREAK:
+		// a soft break is a line ending in the value of the text around it
+		n.Type = "text"
+		n.Value = stringPtr("\n")
+		return n, nil
+	case ast.CODE:
+		n.Type = "inlineCode"
+		n.Value = stringPtr(string(node.Literal))
+		return n, nil
+	case ast.EMPHASIS:
+		n.Type = "emphasis"
+	case ast.STRONG:
+		n.Type = "strong"
+	case ast.LINK:
+		n.Type = "link"
+		n.URL = stringPtr(string(node.Destination))
+		if len(node.Title) > 0 {
+			n.Title = stringPtr(string(node.Title))
+		}
+	case ast.IMAGE:
+		n.Type = "image"
+		n.URL = stringPtr(string(node.Destination))
+		if len(node.Title) > 0 {
+			n.Title = stringPtr(string(node.Title))
+		}
+		n.Alt = stringPtr(string(renderer.PlainText(nil, node)))
+		return n, nil
+	default:
+		return nil, fmt.Errorf("unsupported node type: %q", node.Type)
+	}
+
+	for _, child := range node.Children {
+		c, err := convert(child)
+		if err != nil {
+			return nil, err
+		}
+		// adjacent texts are one text node in mdast
+		if last := lastChild(n); last != nil && last.Type == "text" && c.Type == "text" {
+			*last.Value += *c.Value
+			last.Position.End = c.Position.End
+			continue
+		}
+		n.Children = append(n.Children, c)
+	}
+	return n, nil
+}
+
+// MarshalJSON writes the children of a parent node even if there are none,
+// and leaves out the children of the other nodes.
+func (n *Node) MarshalJSON() ([]byte, error) {
+	type node Node
+	v := struct {
+		*node
+		Children *[]*Node `json:"children,omitempty"`
+	}{node: (*node)(n)}
+
+	switch n.Type {
+	case "text", "inlineCode", "code", "thematicBreak", "image":
+	default:
+		children := n.Children
+		if children == nil {
+			children = []*Node{}
+		}
+		v.Children = &children
+	}
+	return json.Marshal(v)
+}
+
+// Render writes the mdast tree of node, parsed from source, as JSON to w.
+func Render(w io.Writer, node *ast.Node, source []byte) error {
+	n, err := Convert(node, source)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(n)
+}
+
+// utf16Positions converts the positions of a source from bytes and characters to UTF-16 code units.
+type utf16Positions struct {
+	source []byte
+	// lines are the offsets of the starts of the lines in bytes, and units the same in UTF-16 code units.
+	lines []int
+	units []int
+}
+
+func newUTF16Positions(source []byte) *utf16Positions {
+	p := &utf16Positions{source: source, lines: []int{0}, units: []int{0}}
+	units := 0
+	for i := 0; i < len(source); {
+		r, size := utf8.DecodeRune(source[i:])
+		i += size
+		units += utf16Len(r)
+		if r == '\n' || r == '\r' && (i == len(source) || source[i] != '\n') {
+			p.lines = append(p.lines, i)
+			p.units = append(p.units, units)
+		}
+	}
+	return p
+}
+
+// convert replaces the columns and offsets of n and its descendants with UTF-16 code units.
+func (p *utf16Positions) convert(n *Node) {
+	n.Position.Start = p.position(n.Position.Start)
+	n.Position.End = p.position(n.Position.End)
+	for _, child := range n.Children {
+		p.convert(child)
+	}
+}
+
+func (p *utf16Positions) position(pos token.Position) token.Position {
+	offset := pos.Offset
+	if offset > len(p.source) {
+		offset = len(p.source)
+	}
+	line := sort.SearchInts(p.lines, offset+1) - 1
+	column := 0
+	for _, r := range string(p.source[p.lines[line]:offset]) {
+		column += utf16Len(r)
+	}
+	return token.Position{Line: pos.Line, Column: column + 1, Offset: p.units[line] + column}
+}
+
+// utf16Len returns the number of UTF-16 code units of r. A byte that is not UTF-8 is read as U+FFFD.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// spread reports whether any of blocks are separated by a blank line.
+func spread(blocks []*ast.Node) bool {
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i].Pos.Line > blocks[i-1].End.Line+1 {
+			return true
+		}
+	}
+	return false
+}
+
+func lastChild(n *Node) *Node {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[len(n.Children)-1]
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
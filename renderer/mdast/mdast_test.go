@@ -0,0 +1,74 @@
+package mdast
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{
+			"",
+			`{"type":"root","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":1,"column":1,"offset":0}},"children":[]}`,
+		},
+		{
+			"# *a*\nb\nc",
+			`{"type":"root","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":3,"column":2,"offset":9}},"children":[` +
+				`{"type":"heading","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":1,"column":6,"offset":5}},"depth":1,"children":[` +
+				`{"type":"emphasis","position":{"start":{"line":1,"column":3,"offset":2},"end":{"line":1,"column":6,"offset":5}},"children":[` +
+				`{"type":"text","position":{"start":{"line":1,"column":4,"offset":3},"end":{"line":1,"column":5,"offset":4}},"value":"a"}]}]},` +
+				// a soft break is a part of the text around it
+				`{"type":"paragraph","position":{"start":{"line":2,"column":1,"offset":6},"end":{"line":3,"column":2,"offset":9}},"children":[` +
+				`{"type":"text","position":{"start":{"line":2,"column":1,"offset":6},"end":{"line":3,"column":2,"offset":9}},"value":"b\nc"}]}]}`,
+		},
+		{
+			"2. [a](u \"t\") ![b](v)\n\n   ```go x\n   ```",
+			`{"type":"root","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":4,"column":7,"offset":40}},"children":[` +
+				`{"type":"list","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":4,"column":7,"offset":40}},"ordered":true,"start":2,"spread":true,"children":[` +
+				`{"type":"listItem","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":4,"column":7,"offset":40}},"spread":true,"children":[` +
+				`{"type":"paragraph","position":{"start":{"line":1,"column":4,"offset":3},"end":{"line":1,"column":22,"offset":21}},"children":[` +
+				`{"type":"link","position":{"start":{"line":1,"column":4,"offset":3},"end":{"line":1,"column":14,"offset":13}},"url":"u","title":"t","children":[` +
+				`{"type":"text","position":{"start":{"line":1,"column":5,"offset":4},"end":{"line":1,"column":6,"offset":5}},"value":"a"}]},` +
+				`{"type":"text","position":{"start":{"line":1,"column":14,"offset":13},"end":{"line":1,"column":15,"offset":14}},"value":" "},` +
+				`{"type":"image","position":{"start":{"line":1,"column":15,"offset":14},"end":{"line":1,"column":22,"offset":21}},"url":"v","alt":"b"}]},` +
+				`{"type":"code","position":{"start":{"line":3,"column":4,"offset":26},"end":{"line":4,"column":7,"offset":40}},"value":"","lang":"go","meta":"x"}]}]}]}`,
+		},
+		{
+			"|a|b|\n|:-|-|\n",
+			`{"type":"root","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":3,"column":1,"offset":13}},"children":[` +
+				`{"type":"table","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":2,"column":7,"offset":12}},"align":["left",null],"children":[` +
+				`{"type":"tableRow","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":1,"column":6,"offset":5}},"children":[` +
+				`{"type":"tableCell","position":{"start":{"line":1,"column":2,"offset":1},"end":{"line":1,"column":3,"offset":2}},"children":[` +
+				`{"type":"text","position":{"start":{"line":1,"column":2,"offset":1},"end":{"line":1,"column":3,"offset":2}},"value":"a"}]},` +
+				`{"type":"tableCell","position":{"start":{"line":1,"column":4,"offset":3},"end":{"line":1,"column":5,"offset":4}},"children":[` +
+				`{"type":"text","position":{"start":{"line":1,"column":4,"offset":3},"end":{"line":1,"column":5,"offset":4}},"value":"b"}]}]}]}]}`,
+		},
+		{
+			// columns and offsets count UTF-16 code units: é is one and 😀 two
+			"é😀 *a*\r\nb\n",
+			`{"type":"root","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":3,"column":1,"offset":11}},"children":[` +
+				`{"type":"paragraph","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":2,"column":2,"offset":10}},"children":[` +
+				`{"type":"text","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":1,"column":5,"offset":4}},"value":"é😀 "},` +
+				`{"type":"emphasis","position":{"start":{"line":1,"column":5,"offset":4},"end":{"line":1,"column":8,"offset":7}},"children":[` +
+				`{"type":"text","position":{"start":{"line":1,"column":6,"offset":5},"end":{"line":1,"column":7,"offset":6}},"value":"a"}]},` +
+				`{"type":"text","position":{"start":{"line":1,"column":8,"offset":7},"end":{"line":2,"column":2,"offset":10}},"value":"\nb"}]}]}`,
+		},
+	}
+
+	for i, tt := range tests {
+		doc := parser.New(lexer.New([]byte(tt.input))).ParseDocument()
+		var got bytes.Buffer
+		if err := Render(&got, doc, []byte(tt.input)); err != nil {
+			t.Fatalf("tests[%d] - %v", i, err)
+		}
+		if got.String() != tt.want+"\n" {
+			t.Errorf("tests[%d] - json wrong.\nexpected=%s\ngot=%s", i, tt.want, got.String())
+		}
+	}
+}
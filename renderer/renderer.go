@@ -0,0 +1,206 @@
+// Package renderer walks a document tree and hands each node to a Renderer that writes the output format.
+package renderer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/token"
+)
+
+// WalkStatus tells the walk how to go on after a node was visited.
+type WalkStatus int
+
+const (
+	// GoToNext goes on to the children of the node, or to the next node.
+	GoToNext WalkStatus = iota
+	// SkipChildren doesn't visit the children of the node; the node is still exited.
+	SkipChildren
+	// Terminate stops the walk.
+	Terminate
+)
+
+// Renderer writes the nodes of a document tree to w.
+// RenderNode is called with entering set to true before the children of node are visited,
+// and with entering set to false after them.
+// A Renderer may keep what it has written so far, so use a new one for each document.
+type Renderer interface {
+	RenderNode(w io.Writer, node *ast.Node, entering bool) (WalkStatus, error)
+}
+
+// Warning reports a node a Renderer couldn't write as it is in its output format, and how it was written instead.
+type Warning struct {
+	Pos     token.Position
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%d:%d: %s", w.Pos.Line, w.Pos.Column, w.Message)
+}
+
+// Warner is implemented by the Renderers that report the nodes they couldn't write as they are.
+type Warner interface {
+	// Warnings returns the warnings about the nodes rendered so far.
+	Warnings() []Warning
+}
+
+// NodeRendererFunc renders a single kind of node. See Renderer.
+type NodeRendererFunc func(w io.Writer, node *ast.Node, entering bool) (WalkStatus, error)
+
+// Walk visits node and its descendants in document order, calling fn when entering and exiting each of them.
+func Walk(node *ast.Node, fn func(node *ast.Node, entering bool) (WalkStatus, error)) error {
+	_, err := walk(node, fn)
+	return err
+}
+
+func walk(node *ast.Node, fn func(node *ast.Node, entering bool) (WalkStatus, error)) (WalkStatus, error) {
+	status, err := fn(node, true)
+	if err != nil || status == Terminate {
+		return Terminate, err
+	}
+
+	if status != SkipChildren {
+		for _, child := range node.Children {
+			if status, err := walk(child, fn); err != nil || status == Terminate {
+				return Terminate, err
+			}
+		}
+	}
+
+	status, err = fn(node, false)
+	if err != nil || status == Terminate {
+		return Terminate, err
+	}
+	return GoToNext, nil
+}
+
+// Render writes node and its descendants to w with r.
+func Render(w io.Writer, r Renderer, node *ast.Node) error {
+	return Walk(node, func(node *ast.Node, entering bool) (WalkStatus, error) {
+		return r.RenderNode(w, node, entering)
+	})
+}
+
+// Overrides is a Renderer that renders the kinds of nodes registered to it with their own functions,
+// and every other node with a base Renderer.
+type Overrides struct {
+	base  Renderer
+	funcs map[ast.NodeType]NodeRendererFunc
+}
+
+// NewOverrides initializes Overrides on top of base.
+func NewOverrides(base Renderer) *Overrides {
+	return &Overrides{
+		base:  base,
+		funcs: map[ast.NodeType]NodeRendererFunc{},
+	}
+}
+
+// Register renders nodes of type t with fn instead of the base Renderer.
+// fn may call the base Renderer itself to extend its output.
+func (o *Overrides) Register(t ast.NodeType, fn NodeRendererFunc) {
+	o.funcs[t] = fn
+}
+
+// RenderNode implements Renderer.
+func (o *Overrides) RenderNode(w io.Writer, node *ast.Node, entering bool) (WalkStatus, error) {
+	if fn, ok := o.funcs[node.Type]; ok {
+		return fn(w, node, entering)
+	}
+	return o.base.RenderNode(w, node, entering)
+}
+
+// PlainText appends the text of the inlines under node to result without markup.
+func PlainText(result []byte, node *ast.Node) []byte {
+	for _, child := range node.Children {
+		switch child.Type {
+		case ast.TEXT, ast.CODE:
+			result = append(result, child.Literal...)
+		case ast.SOFT_BREAK:
+			result = append(result, ' ')
+		default:
+			result = PlainText(result, child)
+		}
+	}
+	return result
+}
+
+// languageAliases maps the short names of languages in info strings to the names CodeLanguage returns for them.
+var languageAliases = map[string]string{
+	"sh":      "bash",
+	"shell":   "bash",
+	"zsh":     "bash",
+	"c++":     "cpp",
+	"cs":      "csharp",
+	"golang":  "go",
+	"js":      "javascript",
+	"mjs":     "javascript",
+	"jsonc":   "json",
+	"patch":   "diff",
+	"py":      "python",
+	"python3": "python",
+	"rb":      "ruby",
+	"ts":      "typescript",
+	"yml":     "yaml",
+}
+
+// InfoWord returns the first word of the info string info of a code block, which names its language as written.
+func InfoWord(info []byte) []byte {
+	if i := bytes.IndexAny(info, " \t"); i >= 0 {
+		return info[:i]
+	}
+	return info
+}
+
+// CodeLanguage returns the language of a code block with the info string info: its first word in lower case,
+// with short names such as js or py spelled out.
+func CodeLanguage(info []byte) string {
+	language := strings.ToLower(string(InfoWord(info)))
+	if name, ok := languageAliases[language]; ok {
+		return name
+	}
+	return language
+}
+
+// HeadingIDs returns the ids of the headings of doc, like the anchors of GitHub:
+// their text in lower case with spaces replaced by hyphens
+// and punctuation removed, followed by a number if it is used by a previous heading.
+func HeadingIDs(doc *ast.Node) map[*ast.Node]string {
+	ids := map[*ast.Node]string{}
+	used := map[string]bool{}
+	Walk(doc, func(node *ast.Node, entering bool) (WalkStatus, error) {
+		if node.Type != ast.HEADING || !entering {
+			return GoToNext, nil
+		}
+		id := slug(string(PlainText(nil, node)))
+		if id == "" {
+			id = "section"
+		}
+		unique := id
+		for i := 1; used[unique]; i++ {
+			unique = id + "-" + strconv.Itoa(i)
+		}
+		used[unique] = true
+		ids[node] = unique
+		return SkipChildren, nil
+	})
+	return ids
+}
+
+func slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case r == ' ' || r == '-':
+			b.WriteRune('-')
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package renderer_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/html"
+)
+
+func parse(input string) *ast.Node {
+	return parser.New(lexer.New([]byte(input))).ParseDocument()
+}
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		input string
+		skip  ast.NodeType
+		want  string
+	}{
+		{input: "# *Heading1*\n", want: "+DOCUMENT +HEADING +EMPHASIS +TEXT -TEXT -EMPHASIS -HEADING -DOCUMENT"},
+		{input: "> Description1\n\n- List1\n", skip: ast.BLOCK_QUOTE, want: "+DOCUMENT +BLOCK_QUOTE -BLOCK_QUOTE +LIST +LIST_ITEM +PARAGRAPH +TEXT -TEXT -PARAGRAPH -LIST_ITEM -LIST -DOCUMENT"},
+	}
+
+	for i, tt := range tests {
+		var got []string
+		err := renderer.Walk(parse(tt.input), func(node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+			if entering {
+				got = append(got, "+"+string(node.Type))
+			} else {
+				got = append(got, "-"+string(node.Type))
+			}
+			if entering && node.Type == tt.skip {
+				return renderer.SkipChildren, nil
+			}
+			return renderer.GoToNext, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("tests[%d] - walk wrong.\nexpected=%s\ngot=%s", i, tt.want, strings.Join(got, " "))
+		}
+	}
+}
+
+func TestWalkTerminate(t *testing.T) {
+	count := 0
+	err := renderer.Walk(parse("Description1 *Description2*\n"), func(node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+		count++
+		if node.Type == ast.TEXT {
+			return renderer.Terminate, nil
+		}
+		return renderer.GoToNext, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("count wrong. expected=%d, got=%d", 3, count)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	base := html.NewRenderer()
+	r := renderer.NewOverrides(base)
+	r.Register(ast.LINK, func(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+		if !entering {
+			return base.RenderNode(w, node, entering)
+		}
+		_, err := io.WriteString(w, `<a href="`+string(html.AppendEscaped(nil, node.Destination))+`" target="_blank">`)
+		return renderer.GoToNext, err
+	})
+	r.Register(ast.CODE_BLOCK, func(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+		if !entering {
+			return renderer.GoToNext, nil
+		}
+		_, err := io.WriteString(w, "<div class=\"code\">"+string(html.AppendEscaped(nil, node.Literal))+"</div>\n")
+		return renderer.GoToNext, err
+	})
+
+	var got bytes.Buffer
+	if err := renderer.Render(&got, r, parse("[Google](https://www.google.com/)\n\n```\n<code>\n```\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "<p><a href=\"https://www.google.com/\" target=\"_blank\">Google</a></p>\n<div class=\"code\">&lt;code&gt;\n</div>\n"
+	if got.String() != want {
+		t.Errorf("html wrong.\nexpected=%q\ngot=%q", want, got.String())
+	}
+}
+
+func TestCodeLanguage(t *testing.T) {
+	tests := []struct {
+		info string
+		want string
+	}{
+		{"", ""},
+		{"go", "go"},
+		{"Go {1,3}", "go"},
+		{"py", "python"},
+		{"yml\ttitle", "yaml"},
+		{"C++", "cpp"},
+		{"golang", "go"},
+		{"Python3 title", "python"},
+	}
+
+	for i, tt := range tests {
+		if got := renderer.CodeLanguage([]byte(tt.info)); got != tt.want {
+			t.Errorf("tests[%d] - language wrong. expected=%q, got=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestHeadingIDs(t *testing.T) {
+	doc := parse("# Set *up*\n## Set up\n## C++ & Go_1\n## !!\n")
+	ids := renderer.HeadingIDs(doc)
+	want := []string{"set-up", "set-up-1", "c--go_1", "section"}
+	for i, heading := range doc.Children {
+		if ids[heading] != want[i] {
+			t.Errorf("tests[%d] - id wrong. expected=%q, got=%q", i, want[i], ids[heading])
+		}
+	}
+}
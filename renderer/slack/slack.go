@@ -0,0 +1,209 @@
+// Package slack renders a document tree as the mrkdwn markup of Slack messages.
+package slack
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/text"
+)
+
+// Renderer renders a document tree as mrkdwn.
+// mrkdwn has no headings, so they are written as bold plain text. Tables are written as preformatted text,
+// images as links to them, thematic breaks as a line of hyphens and nested quotes as part of the outer ones.
+// Each of these is reported with a warning.
+// It keeps whether a block was written, and the warnings.
+type Renderer struct {
+	// started is true once a block was written.
+	started  bool
+	warnings []renderer.Warning
+}
+
+// NewRenderer initializes Renderer.
+func NewRenderer() *Renderer {
+	return &Renderer{}
+}
+
+// Warnings implements renderer.Warner.
+func (r *Renderer) Warnings() []renderer.Warning {
+	return r.warnings
+}
+
+// RenderNode implements renderer.Renderer. Each block is written as a whole when it is entered.
+func (r *Renderer) RenderNode(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+	if node.Type == ast.DOCUMENT || !entering {
+		return renderer.GoToNext, nil
+	}
+
+	var result []byte
+	if r.started {
+		result = append(result, '\n')
+	}
+	r.started = true
+	result, err := r.appendBlock(result, node)
+	if err != nil {
+		return renderer.Terminate, err
+	}
+	_, err = w.Write(result)
+	return renderer.SkipChildren, err
+}
+
+// appendBlocks writes blocks separated by a blank line, or by a line break if tight.
+func (r *Renderer) appendBlocks(result []byte, blocks []*ast.Node, tight bool) ([]byte, error) {
+	for i, block := range blocks {
+		if i > 0 && !tight {
+			result = append(result, '\n')
+		}
+		var err error
+		if result, err = r.appendBlock(result, block); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// appendBlock writes a block; the result always ends with a line feed.
+func (r *Renderer) appendBlock(result []byte, node *ast.Node) ([]byte, error) {
+	var err error
+	switch node.Type {
+	case ast.HEADING:
+		r.warn(node, "heading written as bold text")
+		result = appendStr(result, "*")
+		result = appendEscaped(result, bytes.TrimSpace(renderer.PlainText(nil, node)))
+		result = appendStr(result, "*\n")
+	case ast.PARAGRAPH:
+		if result, err = r.appendInlines(result, node); err != nil {
+			return nil, err
+		}
+		result = append(result, '\n')
+	case ast.BLOCK_QUOTE:
+		inner, err := r.appendBlocks(nil, node.Children, false)
+		if err != nil {
+			return nil, err
+		}
+		for parent := node.Parent; parent != nil; parent = parent.Parent {
+			if parent.Type == ast.BLOCK_QUOTE {
+				r.warn(node, "nested quote written as part of the outer one")
+				return append(result, inner...), nil
+			}
+		}
+		result = text.AppendPrefixed(result, inner, "> ", "> ")
+	case ast.LIST:
+		for i, item := range node.Children {
+			marker := "• "
+			if node.Ordered {
+				marker = strconv.Itoa(node.Start+i) + ". "
+			}
+			inner, err := r.appendBlocks(nil, item.Children, node.Tight)
+			if err != nil {
+				return nil, err
+			}
+			if len(inner) == 0 {
+				inner = []byte("\n")
+			}
+			if i > 0 && !node.Tight {
+				result = append(result, '\n')
+			}
+			result = text.AppendPrefixed(result, inner, marker, strings.Repeat(" ", text.Width(marker)))
+		}
+	case ast.CODE_BLOCK:
+		result = appendStr(result, "```\n")
+		result = appendEscaped(result, node.Literal)
+		if len(node.Literal) > 0 && node.Literal[len(node.Literal)-1] != '\n' {
+			result = append(result, '\n')
+		}
+		result = appendStr(result, "```\n")
+	case ast.HORIZON:
+		r.warn(node, "thematic break written as a line of hyphens")
+		result = appendStr(result, "---\n")
+	case ast.TABLE:
+		r.warn(node, "table written as preformatted text")
+		result = appendStr(result, "```\n")
+		result = appendEscaped(result, text.AppendTable(nil, node))
+		result = appendStr(result, "```\n")
+	default:
+		return nil, fmt.Errorf("unsupported node type: %q", node.Type)
+	}
+	return result, nil
+}
+
+func (r *Renderer) appendInlines(result []byte, node *ast.Node) ([]byte, error) {
+	for _, child := range node.Children {
+		var err error
+		switch child.Type {
+		case ast.TEXT:
+			result = appendEscaped(result, child.Literal)
+		case ast.SOFT_BREAK:
+			result = append(result, '\n')
+		case ast.CODE:
+			result = appendStr(result, "`")
+			result = appendEscaped(result, child.Literal)
+			result = appendStr(result, "`")
+		case ast.EMPHASIS, ast.STRONG:
+			delimiter := "_"
+			if child.Type == ast.STRONG {
+				delimiter = "*"
+			}
+			result = appendStr(result, delimiter)
+			if result, err = r.appendInlines(result, child); err != nil {
+				return nil, err
+			}
+			result = appendStr(result, delimiter)
+		case ast.LINK, ast.IMAGE:
+			if child.Type == ast.IMAGE {
+				r.warn(child, "image written as a link")
+			}
+			result = appendLink(result, child.Destination, renderer.PlainText(nil, child))
+		default:
+			return nil, fmt.Errorf("unsupported node type: %q", child.Type)
+		}
+	}
+	return result, nil
+}
+
+func (r *Renderer) warn(node *ast.Node, message string) {
+	r.warnings = append(r.warnings, renderer.Warning{Pos: node.Pos, Message: message})
+}
+
+// appendLink writes a link as <url|text>, or <url> if the text is the url itself or empty.
+func appendLink(result []byte, url []byte, text []byte) []byte {
+	result = appendStr(result, "<")
+	result = appendDestination(result, url)
+	if len(text) > 0 && !bytes.Equal(text, url) {
+		result = appendStr(result, "|")
+		result = appendEscaped(result, text)
+	}
+	return appendStr(result, ">")
+}
+
+// appendEscaped appends chs to slice, escaping the characters that are control characters of mrkdwn.
+func appendEscaped(slice []byte, chs []byte) []byte {
+	for _, ch := range chs {
+		switch ch {
+		case '&':
+			slice = appendStr(slice, "&amp;")
+		case '<':
+			slice = appendStr(slice, "&lt;")
+		case '>':
+			slice = appendStr(slice, "&gt;")
+		default:
+			slice = append(slice, ch)
+		}
+	}
+	return slice
+}
+
+// appendDestination appends the url of a link, escaped like text, with a "|" percent-encoded
+// so that it doesn't end the url and start the text.
+func appendDestination(slice []byte, url []byte) []byte {
+	return appendEscaped(slice, bytes.ReplaceAll(url, []byte("|"), []byte("%7C")))
+}
+
+func appendStr(slice []byte, str string) []byte {
+	return append(slice, str...)
+}
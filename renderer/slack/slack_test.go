@@ -0,0 +1,60 @@
+package slack
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+type expected struct {
+	input string
+	want  string
+}
+
+func compareGotAndWant(t *testing.T, tests []expected) {
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		if err := parser.New(lexer.New([]byte(tt.input))).RenderTo(&buf, NewRenderer()); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("tests[%d] - mrkdwn wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []expected{
+		{input: "# Heading *1*\nDescription1\nDescription2\n", want: "*Heading 1*\n\nDescription1\nDescription2\n"},
+		{input: "*a* **b** `c` a & <b>\n", want: "_a_ *b* `c` a &amp; &lt;b&gt;\n"},
+		{input: "[a](https://example.com) [https://example.com](https://example.com)\n", want: "<https://example.com|a> <https://example.com>\n"},
+		{input: "[PR](https://x/y?a=1|2&b=<c>)\n", want: "<https://x/y?a=1%7C2&amp;b=&lt;c&gt;|PR>\n"},
+		{input: "> a\n>\n> b\n", want: "> a\n>\n> b\n"},
+		{input: "- List1\n- List2\n  1. List2_1\n  2. List2_2\n", want: "• List1\n• List2\n  1. List2_1\n  2. List2_2\n"},
+		{input: "3. a\n\n4. b\n", want: "3. a\n\n4. b\n"},
+		{input: "```go\nif a < b {}\n```\n", want: "```\nif a &lt; b {}\n```\n"},
+		{input: "| a | bb |\n| --- | --- |\n| ccc | d |\n", want: "```\na    bb\n---  --\nccc  d\n```\n"},
+		{input: "![a](b.png)\n***\n", want: "<b.png|a>\n\n---\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestWarnings(t *testing.T) {
+	r := NewRenderer()
+	if err := parser.New(lexer.New([]byte("> > a\n\n![b](c.png)\n\n# d\n"))).RenderTo(&bytes.Buffer{}, r); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, warning := range r.Warnings() {
+		got = append(got, warning.String())
+	}
+	want := []string{"1:3: nested quote written as part of the outer one", "3:1: image written as a link", "5:1: heading written as bold text"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings wrong.\nexpected=%q\ngot=%q", want, got)
+	}
+}
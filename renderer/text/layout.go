@@ -0,0 +1,98 @@
+package text
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+)
+
+// RuneWidth returns the number of columns r takes in a terminal or in a monospace font:
+// 2 for wide characters such as CJK, 0 for combining marks, and 1 otherwise.
+func RuneWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r):
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0xa4cf,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Width returns the number of columns s takes in a terminal or in a monospace font.
+func Width(s string) int {
+	width := 0
+	for _, r := range s {
+		width += RuneWidth(r)
+	}
+	return width
+}
+
+// AppendPrefixed appends the lines of inner, each ending with a line feed, to result,
+// starting the first one with first and the others with rest. Blank lines are written without trailing spaces.
+func AppendPrefixed(result []byte, inner []byte, first, rest string) []byte {
+	prefix := first
+	for len(inner) > 0 {
+		i := bytes.IndexByte(inner, '\n')
+		line := inner[:i]
+		inner = inner[i+1:]
+
+		if len(line) == 0 {
+			result = append(result, strings.TrimRight(prefix, " ")...)
+		} else {
+			result = append(result, prefix...)
+			result = append(result, line...)
+		}
+		result = append(result, '\n')
+		prefix = rest
+	}
+	return result
+}
+
+// AppendTable appends the rows of table to result as lines of plain text,
+// with the columns padded to the same width and the header row underlined with hyphens.
+func AppendTable(result []byte, table *ast.Node) []byte {
+	var rows [][]string
+	var widths []int
+	for _, row := range table.Children {
+		var cells []string
+		for i, cell := range row.Children {
+			text := string(renderer.PlainText(nil, cell))
+			cells = append(cells, text)
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if width := Width(text); width > widths[i] {
+				widths[i] = width
+			}
+		}
+		rows = append(rows, cells)
+	}
+
+	for i, cells := range rows {
+		var line []string
+		for j, text := range cells {
+			line = append(line, text+strings.Repeat(" ", widths[j]-Width(text)))
+		}
+		result = append(result, strings.TrimRight(strings.Join(line, "  "), " ")+"\n"...)
+		if i == 0 {
+			var rule []string
+			for _, width := range widths {
+				rule = append(rule, strings.Repeat("-", width))
+			}
+			result = append(result, strings.Join(rule, "  ")+"\n"...)
+		}
+	}
+	return result
+}
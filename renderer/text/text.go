@@ -0,0 +1,161 @@
+// Package text renders a document tree as plain text without markup, for search indexes and previews.
+// It also lays out text in columns for the renderers that write for a terminal or a monospace font.
+package text
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/istsh/markdown-viewer/ast"
+	"github.com/istsh/markdown-viewer/renderer"
+)
+
+// Renderer renders a document tree as plain text.
+// Each heading, paragraph, list item and table row is written on a line of its own, and blocks are separated by a blank line,
+// except the items of a tight list. Links are written as their text, images as their description,
+// and code is written as it is.
+// It keeps whether a block was written and the tight list the last one was in.
+type Renderer struct {
+	// started is true once a block was written.
+	started bool
+	// list is the tight list the last block was in, whose items are not separated by a blank line.
+	list *ast.Node
+}
+
+// NewRenderer initializes Renderer.
+func NewRenderer() *Renderer {
+	return &Renderer{}
+}
+
+// RenderNode implements renderer.Renderer.
+func (r *Renderer) RenderNode(w io.Writer, node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+	if !entering {
+		return renderer.GoToNext, nil
+	}
+
+	var result []byte
+	switch node.Type {
+	case ast.DOCUMENT, ast.BLOCK_QUOTE, ast.LIST, ast.LIST_ITEM:
+		return renderer.GoToNext, nil
+	case ast.HORIZON:
+		return renderer.SkipChildren, nil
+	case ast.HEADING, ast.PARAGRAPH:
+		result = r.startBlock(result, node)
+		result = renderer.PlainText(result, node)
+		result = append(result, '\n')
+	case ast.CODE_BLOCK:
+		result = r.startBlock(result, node)
+		result = append(result, node.Literal...)
+		if len(node.Literal) > 0 && node.Literal[len(node.Literal)-1] != '\n' {
+			result = append(result, '\n')
+		}
+	case ast.TABLE:
+		result = r.startBlock(result, node)
+		for _, row := range node.Children {
+			for i, cell := range row.Children {
+				if i > 0 {
+					result = append(result, '\t')
+				}
+				result = renderer.PlainText(result, cell)
+			}
+			result = append(result, '\n')
+		}
+	default:
+		return renderer.Terminate, fmt.Errorf("unsupported node type: %q", node.Type)
+	}
+
+	_, err := w.Write(result)
+	return renderer.SkipChildren, err
+}
+
+// startBlock separates the block node from the previous one with a blank line, or with just the line break
+// if both are in the same tight list.
+func (r *Renderer) startBlock(result []byte, node *ast.Node) []byte {
+	list := tightList(node)
+	if r.started && (list == nil || list != r.list) {
+		result = append(result, '\n')
+	}
+	r.started = true
+	r.list = list
+	return result
+}
+
+// tightList returns the outermost list node is in if the innermost one is tight, or nil.
+func tightList(node *ast.Node) *ast.Node {
+	var list *ast.Node
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		if parent.Type != ast.LIST {
+			continue
+		}
+		if list == nil && !parent.Tight {
+			return nil
+		}
+		list = parent
+	}
+	return list
+}
+
+// Summary returns the first sentence of the paragraphs of doc, cut at a space to at most n characters with an ellipsis.
+// Headings, code blocks and tables are left out, and the whitespace is collapsed to single spaces.
+func Summary(doc *ast.Node, n int) string {
+	var words []string
+	renderer.Walk(doc, func(node *ast.Node, entering bool) (renderer.WalkStatus, error) {
+		switch node.Type {
+		case ast.HEADING, ast.CODE_BLOCK, ast.TABLE:
+			return renderer.SkipChildren, nil
+		case ast.PARAGRAPH:
+			if entering {
+				words = append(words, strings.Fields(string(renderer.PlainText(nil, node)))...)
+			}
+			return renderer.SkipChildren, nil
+		}
+		return renderer.GoToNext, nil
+	})
+	text := strings.Join(words, " ")
+
+	if end := sentenceEnd(text); end > 0 {
+		text = text[:end]
+	}
+	if n <= 0 || utf8.RuneCountInString(text) <= n {
+		return text
+	}
+
+	// the ellipsis takes the last character
+	cut := text[:runeOffset(text, n-1)]
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) }) + "…"
+}
+
+// sentenceEnd returns the offset just after the first sentence of text, or 0 if it has only one.
+// A sentence ends with `.`, `!` or `?` followed by a space, or with `。`, `！` or `？`.
+func sentenceEnd(text string) int {
+	for i, r := range text {
+		switch r {
+		case '.', '!', '?':
+			if next := i + 1; next < len(text) && text[next] == ' ' {
+				return next
+			}
+		case '。', '！', '？':
+			if next := i + utf8.RuneLen(r); next < len(text) {
+				return next
+			}
+		}
+	}
+	return 0
+}
+
+// runeOffset returns the byte offset of the n-th character of s.
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
@@ -0,0 +1,64 @@
+package text
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+)
+
+type expected struct {
+	input string
+	want  string
+}
+
+func compareGotAndWant(t *testing.T, tests []expected) {
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		if err := parser.New(lexer.New([]byte(tt.input))).RenderTo(&buf, NewRenderer()); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("tests[%d] - text wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []expected{
+		{input: "# Heading1\nDescription1\nDescription2\n", want: "Heading1\n\nDescription1 Description2\n"},
+		{input: "*a* **b** `c` [d](e) ![f](g)\n", want: "a b c d f\n"},
+		{input: "> a\n>\n> > b\n", want: "a\n\nb\n"},
+		{input: "a\n- List1\n- List2\n  - List2_1\n\nb\n", want: "a\n\nList1\nList2\nList2_1\n\nb\n"},
+		{input: "- List1\n\n- List2\n", want: "List1\n\nList2\n"},
+		{input: "```go\nfmt.Println(\"a\")\n```\n", want: "fmt.Println(\"a\")\n"},
+		{input: "a\n\n***\n\nb\n", want: "a\n\nb\n"},
+		{input: "| a | b |\n| - | - |\n| *c* | d |\n", want: "a\tb\nc\td\n"},
+	}
+
+	compareGotAndWant(t, tests)
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		input string
+		n     int
+		want  string
+	}{
+		{"# Title\n\nFirst *sentence*. Second sentence.\n", 100, "First sentence."},
+		{"First\nline without an end\n", 0, "First line without an end"},
+		{"```\ncode\n```\n\n- item one\n- item two\n", 100, "item one item two"},
+		{"A long sentence, that is cut.\n", 14, "A long…"},
+		{"A longer sentence.\n", 10, "A longer…"},
+		{"日本語の文章です。次の文。\n", 4, "日本語…"},
+		{"# Title\n", 10, ""},
+	}
+
+	for i, tt := range tests {
+		doc := parser.New(lexer.New([]byte(tt.input))).ParseDocument()
+		if got := Summary(doc, tt.n); got != tt.want {
+			t.Errorf("tests[%d] - summary wrong. expected=%q, got=%q", i, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+	"github.com/istsh/markdown-viewer/renderer"
+	"github.com/istsh/markdown-viewer/renderer/document"
+	"github.com/istsh/markdown-viewer/renderer/docx"
+	"github.com/istsh/markdown-viewer/renderer/highlight"
+	"github.com/istsh/markdown-viewer/renderer/markdown"
+	"github.com/istsh/markdown-viewer/renderer/mdast"
+	"github.com/istsh/markdown-viewer/renderer/text"
+	"github.com/istsh/markdown-viewer/token"
+)
+
+// markups are the formats of /parse for the markup of other services, with the media types of their results.
+// The warnings of the renderers are sent in X-Markdown-Warning headers.
+var markups = map[string]string{
+	"slack":      "text/plain; charset=utf-8",
+	"jira":       "text/plain; charset=utf-8",
+	"confluence": "application/xhtml+xml; charset=utf-8",
+}
+
+// serveConfig holds the settings of the server.
+type serveConfig struct {
+	// document lays out the html documents of /parse?format=document and the pages of root.
+	document document.Options
+	// root is the directory whose files are served, if any.
+	root string
+}
+
+type Input struct {
+	Markdown string `json:"markdown"`
+}
+
+// serve starts the HTTP server.
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "listen on `address`")
+	documentOptions := addDocumentFlags(fs)
+	var config serveConfig
+	fs.StringVar(&config.root, "root", "", "serve the files under `DIR`, with markdown files as html pages")
+	fs.Parse(args)
+
+	var err error
+	if config.document, err = documentOptions(); err != nil {
+		return err
+	}
+	if config.root != "" && !isDir(config.root) {
+		return fmt.Errorf("%s is not a directory", config.root)
+	}
+	return http.ListenAndServe(*addr, newServeMux(config))
+}
+
+func newServeMux(config serveConfig) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+	mux.HandleFunc("/parse", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			format := r.URL.Query().Get("format")
+			_, markup := markups[format]
+			if format != "" && format != "html" && format != "document" && format != "mdast" && format != "text" && !markup {
+				http.Error(w, "Invalid format: "+format, http.StatusBadRequest)
+				return
+			}
+
+			highlighted, ok := queryBool(w, r, "highlight")
+			if !ok {
+				return
+			}
+			lineNumbers, ok := queryBool(w, r, "line-numbers")
+			if !ok {
+				return
+			}
+
+			if format == "document" {
+				opts := config.document
+				opts.LineNumbers = lineNumbers
+				if theme := r.URL.Query().Get("theme"); theme != "" {
+					if !document.IsTheme(theme) {
+						http.Error(w, "Invalid theme: "+theme, http.StatusBadRequest)
+						return
+					}
+					opts.Theme = theme
+				}
+				body, _, err := newRequestReader(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				// the page is written once it is complete, so that a failure of the layout can be reported
+				var buf bytes.Buffer
+				if err := renderDocument(&buf, body, opts); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write(buf.Bytes())
+				return
+			}
+
+			if format == "mdast" {
+				// the tree is written as a whole, and its positions are converted with the source
+				body, _, err := newRequestReader(r)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				source, err := io.ReadAll(body)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				tree, err := mdast.Convert(parser.New(lexer.New(source)).ParseDocument(), source)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(tree)
+				return
+			}
+
+			l, markdown, err := newRequestLexer(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if format == "text" {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				if err := parser.New(l).RenderTo(w, text.NewRenderer()); err != nil {
+					log.Printf("/parse: %v", err)
+				}
+				return
+			}
+
+			if markup {
+				// the warnings are sent before the result, so the document is converted first
+				mr := formats[format].newRenderer(renderOptions{})
+				var buf bytes.Buffer
+				if err := parser.New(l).RenderTo(&buf, mr); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				for _, warning := range mr.(renderer.Warner).Warnings() {
+					w.Header().Add("X-Markdown-Warning", warning.String())
+				}
+				w.Header().Set("Content-Type", markups[format])
+				w.Write(buf.Bytes())
+				return
+			}
+
+			if markdown {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			} else {
+				w.Header().Set("Content-Type", "application/json")
+			}
+
+			p := parser.New(l)
+			if highlighted || lineNumbers {
+				err = p.RenderTo(w, highlight.NewRenderer(highlight.Options{LineNumbers: lineNumbers}))
+			} else {
+				err = p.ParseTo(w)
+			}
+			if err != nil {
+				// the response is already being written, so the error can only be logged
+				log.Printf("/parse: %v", err)
+			}
+		} else {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/format", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			return
+		}
+		width := defaultFormatWidth
+		if s := r.URL.Query().Get("width"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				http.Error(w, "Invalid width: "+s, http.StatusBadRequest)
+				return
+			}
+			width = n
+		}
+
+		l, _, err := newRequestLexer(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		doc := parser.New(l).ParseDocument()
+		if err := l.Err(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write(markdown.Render(doc, markdown.Options{PreserveLineEnding: true, Width: width}))
+	})
+	mux.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format != "docx" {
+			http.Error(w, "Invalid format: "+format, http.StatusBadRequest)
+			return
+		}
+
+		l, _, err := newRequestLexer(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		doc := parser.New(l).ParseDocument()
+		if err := l.Err(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// the archive is written after the document is converted, so that a failure can be reported
+		var buf bytes.Buffer
+		if err := docx.Render(&buf, doc); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", docx.ContentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="document.docx"`)
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/tokens", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+			return
+		}
+		l, _, err := newRequestLexer(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tokens := []token.Token{}
+		for {
+			tok := l.NextToken()
+			if tok.Type == token.EOF {
+				break
+			}
+			tokens = append(tokens, tok)
+		}
+		if err := l.Err(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	})
+	if config.root != "" {
+		// the other endpoints take precedence over the files of the same names
+		mux.Handle("/", &browser{root: config.root, opts: config.document})
+	}
+	return mux
+}
+
+// queryBool returns the boolean query parameter name of r, which is false if it isn't given.
+// If it can't be read, the request is answered with an error and ok is false.
+func queryBool(w http.ResponseWriter, r *http.Request, name string) (value bool, ok bool) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return false, true
+	}
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		http.Error(w, "Invalid "+name+": "+s, http.StatusBadRequest)
+		return false, false
+	}
+	return value, true
+}
+
+// newRequestLexer returns a Lexer for the markdown text of a request. See newRequestReader.
+func newRequestLexer(r *http.Request) (l *lexer.Lexer, markdown bool, err error) {
+	body, markdown, err := newRequestReader(r)
+	if err != nil {
+		return nil, false, err
+	}
+	return lexer.NewReader(body), markdown, nil
+}
+
+// newRequestReader returns the markdown text of a request:
+// the body itself if its type is text/markdown, reported by markdown, or else the markdown field of a JSON body.
+func newRequestReader(r *http.Request) (body io.Reader, markdown bool, err error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/markdown") {
+		// the markdown text is converted as it is read
+		return r.Body, true, nil
+	}
+
+	input := &Input{}
+	if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+		return nil, false, err
+	}
+	return strings.NewReader(input.Markdown), false, nil
+}
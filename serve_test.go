@@ -0,0 +1,231 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/istsh/markdown-viewer/renderer/document"
+	"github.com/istsh/markdown-viewer/renderer/docx"
+)
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+		want        string
+	}{
+		{
+			"text/markdown",
+			"# a\n",
+			http.StatusOK,
+			`[{"type":"HEADING1","literal":"# ","pos":{"line":1,"column":1,"offset":0}},` +
+				`{"type":"STRING","literal":"a","pos":{"line":1,"column":3,"offset":2}},` +
+				`{"type":"LINE_FEED_CODE","literal":"\n","pos":{"line":1,"column":4,"offset":3}}]` + "\n",
+		},
+		{
+			"application/json",
+			`{"markdown":""}`,
+			http.StatusOK,
+			"[]\n",
+		},
+		{
+			"application/json",
+			`{`,
+			http.StatusBadRequest,
+			"unexpected EOF\n",
+		},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest("POST", "/tokens", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		newServeMux(serveConfig{}).ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("tests[%d] - status wrong. expected=%d, got=%d", i, tt.status, rec.Code)
+		}
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("tests[%d] - body wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		format      string
+		status      int
+		contentType string
+		want        string
+	}{
+		{"", http.StatusOK, "text/html; charset=utf-8", "<p>a</p>\n"},
+		{"mdast", http.StatusOK, "application/json", `{"type":"root","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":1,"column":2,"offset":1}},"children":[` +
+			`{"type":"paragraph","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":1,"column":2,"offset":1}},"children":[` +
+			`{"type":"text","position":{"start":{"line":1,"column":1,"offset":0},"end":{"line":1,"column":2,"offset":1}},"value":"a"}]}]}` + "\n"},
+		{"text", http.StatusOK, "text/plain; charset=utf-8", "a\n"},
+		{"slack", http.StatusOK, "text/plain; charset=utf-8", "a\n"},
+		{"jira", http.StatusOK, "text/plain; charset=utf-8", "a\n"},
+		{"confluence", http.StatusOK, "application/xhtml+xml; charset=utf-8", "<p>a</p>\n"},
+		{"pdf", http.StatusBadRequest, "text/plain; charset=utf-8", "Invalid format: pdf\n"},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest("POST", "/parse?format="+tt.format, strings.NewReader("a"))
+		req.Header.Set("Content-Type", "text/markdown")
+		rec := httptest.NewRecorder()
+		newServeMux(serveConfig{}).ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("tests[%d] - status wrong. expected=%d, got=%d", i, tt.status, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("tests[%d] - content type wrong. expected=%q, got=%q", i, tt.contentType, got)
+		}
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("tests[%d] - body wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestParseBadRequest(t *testing.T) {
+	req := httptest.NewRequest("POST", "/parse", strings.NewReader(`{`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	newServeMux(serveConfig{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status wrong. expected=%d, got=%d", http.StatusBadRequest, rec.Code)
+	}
+	if want, got := "unexpected EOF\n", rec.Body.String(); got != want {
+		t.Errorf("body wrong.\nexpected=%q\ngot=%q", want, got)
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	req := httptest.NewRequest("POST", "/parse?format=slack", strings.NewReader("| a |\n| - |\n\n---\n"))
+	req.Header.Set("Content-Type", "text/markdown")
+	rec := httptest.NewRecorder()
+	newServeMux(serveConfig{}).ServeHTTP(rec, req)
+
+	want := []string{"1:1: table written as preformatted text", "4:1: thematic break written as a line of hyphens"}
+	if got := rec.Header().Values("X-Markdown-Warning"); !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings wrong.\nexpected=%q\ngot=%q", want, got)
+	}
+}
+
+func TestParseHighlight(t *testing.T) {
+	tests := []struct {
+		query  string
+		status int
+		want   string
+	}{
+		{"", http.StatusOK, "<pre><code class=\"language-sh\">echo\n</code></pre>\n"},
+		{"?highlight=true", http.StatusOK, "<pre><code class=\"language-sh\"><span class=\"tok-keyword\">echo</span>\n</code></pre>\n"},
+		{"?line-numbers=1", http.StatusOK, "<pre><code class=\"language-sh\"><span class=\"line\"><span class=\"line-number\" data-line=\"1\"></span>" +
+			"<span class=\"tok-keyword\">echo</span>\n</span></code></pre>\n"},
+		{"?highlight=yes", http.StatusBadRequest, "Invalid highlight: yes\n"},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest("POST", "/parse"+tt.query, strings.NewReader("```sh\necho\n```\n"))
+		req.Header.Set("Content-Type", "text/markdown")
+		rec := httptest.NewRecorder()
+		newServeMux(serveConfig{}).ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("tests[%d] - status wrong. expected=%d, got=%d", i, tt.status, rec.Code)
+		}
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("tests[%d] - body wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	layout := template.Must(template.New("test").Parse("{{.Title}}|{{.Theme}}|{{.Body}}"))
+	tests := []struct {
+		query       string
+		contentType string
+		body        string
+		status      int
+		want        string
+	}{
+		{"", "text/markdown", "---\ntitle: a\n---\n# b\n", http.StatusOK, "a|light|<h1>b</h1>\n"},
+		{"&theme=dark", "application/json", `{"markdown":"# b"}`, http.StatusOK, "b|dark|<h1>b</h1>\n"},
+		{"&theme=blue", "text/markdown", "# b\n", http.StatusBadRequest, "Invalid theme: blue\n"},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest("POST", "/parse?format=document"+tt.query, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		newServeMux(serveConfig{document: document.Options{Template: layout}}).ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("tests[%d] - status wrong. expected=%d, got=%d", i, tt.status, rec.Code)
+		}
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("tests[%d] - body wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		query  string
+		body   string
+		status int
+		want   string
+	}{
+		{"", "# Heading\n    code\n***\n", http.StatusOK, "# Heading\n\n```\ncode\n```\n\n---\n"},
+		{"?width=5", "aaa bbb ccc\r\n", http.StatusOK, "aaa\r\nbbb\r\nccc\r\n"},
+		{"?width=0", "aaa\nbbb\n", http.StatusOK, "aaa\nbbb\n"},
+		{"?width=-1", "a", http.StatusBadRequest, "Invalid width: -1\n"},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest("POST", "/format"+tt.query, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "text/markdown")
+		rec := httptest.NewRecorder()
+		newServeMux(serveConfig{}).ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("tests[%d] - status wrong. expected=%d, got=%d", i, tt.status, rec.Code)
+		}
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("tests[%d] - body wrong.\nexpected=%q\ngot=%q", i, tt.want, got)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	req := httptest.NewRequest("POST", "/export?format=docx", strings.NewReader("# Heading\n"))
+	req.Header.Set("Content-Type", "text/markdown")
+	rec := httptest.NewRecorder()
+	newServeMux(serveConfig{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status wrong. expected=%d, got=%d", http.StatusOK, rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != docx.ContentType {
+		t.Errorf("Content-Type wrong. expected=%q, got=%q", docx.ContentType, got)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len())); err != nil {
+		t.Errorf("document is not a zip archive: %v", err)
+	}
+
+	req = httptest.NewRequest("POST", "/export?format=pdf", strings.NewReader("# Heading\n"))
+	req.Header.Set("Content-Type", "text/markdown")
+	rec = httptest.NewRecorder()
+	newServeMux(serveConfig{}).ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status wrong. expected=%d, got=%d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package main
+
+import "os"
+
+// terminalWidth always returns 0: the width of the terminal is only known on linux and darwin.
+func terminalWidth(f *os.File) int {
+	return 0
+}
@@ -1,5 +1,11 @@
 package token
 
+import (
+	"encoding/json"
+	"strconv"
+	"unicode/utf8"
+)
+
 type TokenType string
 
 const (
@@ -23,32 +29,21 @@ const (
 	LIST_BEGIN            = "LIST_BEGIN"
 	LIST_FINISH           = "LIST_FINISH"
 
-	BACK_QUOTE_BEGIN  = "BACK_QUOTE_BEGIN"
-	BACK_QUOTE_FINISH = "BACK_QUOTE_FINISH"
+	BACK_QUOTE = "BACK_QUOTE"
 
-	ASTERISK_ITALIC_BEGIN       = "ASTERISK_ITALIC_BEGIN"
-	ASTERISK_ITALIC_FINISH      = "ASTERISK_ITALIC_FINISH"
-	ASTERISK_BOLD_BEGIN         = "ASTERISK_BOLD_BEGIN"
-	ASTERISK_BOLD_FINISH        = "ASTERISK_BOLD_FINISH"
-	ASTERISK_ITALIC_BOLD_BEGIN  = "ASTERISK_ITALIC_BOLD_BEGIN"
-	ASTERISK_ITALIC_BOLD_FINISH = "ASTERISK_ITALIC_BOLD_FINISH"
+	CODE_FENCE = "CODE_FENCE"
 
-	UNDER_SCORE_ITALIC_BEGIN       = "UNDER_SCORE_ITALIC_BEGIN"
-	UNDER_SCORE_ITALIC_FINISH      = "UNDER_SCORE_ITALIC_FINISH"
-	UNDER_SCORE_BOLD_BEGIN         = "UNDER_SCORE_BOLD_BEGIN"
-	UNDER_SCORE_BOLD_FINISH        = "UNDER_SCORE_BOLD_FINISH"
-	UNDER_SCORE_ITALIC_BOLD_BEGIN  = "UNDER_SCORE_ITALIC_BOLD_BEGIN"
-	UNDER_SCORE_ITALIC_BOLD_FINISH = "UNDER_SCORE_ITALIC_BOLD_FINISH"
+	ASTERISK    = "ASTERISK"
+	UNDER_SCORE = "UNDER_SCORE"
 
-	CITATION1 = "CITATION1"
-	CITATION2 = "CITATION2"
+	CITATION = "CITATION"
 
 	HORIZON = "HORIZON"
 
-	LINK_TEXT_BEGIN  = "LINK_TEXT_BEGIN"
-	LINK_TEXT_FINISH = "LINK_TEXT_FINISH"
-	LINK_BEGIN       = "LINK_BEGIN"
-	LINK_FINISH      = "LINK_FINISH"
+	LBRACKET = "LBRACKET"
+	RBRACKET = "RBRACKET"
+	LPAREN   = "LPAREN"
+	RPAREN   = "RPAREN"
 
 	TAB1 = "TAB1"
 	TAB2 = "TAB2"
@@ -59,11 +54,63 @@ const (
 	LINE_FEED_CODE = "LINE_FEED_CODE"
 
 	HYPHEN = "HYPHEN"
+
+	ORDERED_LIST_MARKER = "ORDERED_LIST_MARKER"
 )
 
 type Token struct {
 	Type    TokenType
 	Literal []byte
+	Pos     Position
+}
+
+// Position is where a token starts in the input.
+// Line and Column count from 1, and Column counts characters rather than bytes.
+// Offset is the number of bytes before the token.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// Advance returns the position after literal, which starts at p.
+func (p Position) Advance(literal []byte) Position {
+	p.Offset += len(literal)
+	for i, ch := range literal {
+		switch {
+		case ch == '\n', ch == '\r' && (i+1 == len(literal) || literal[i+1] != '\n'):
+			p.Line++
+			p.Column = 1
+		case utf8.RuneStart(ch):
+			// continuation bytes of a multibyte character don't take a column
+			p.Column++
+		}
+	}
+	return p
+}
+
+// String returns the position as "line:column".
+func (p Position) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
+// String returns the position, type and quoted literal of the token, such as `1:1 HEADING1 "# "`.
+func (t Token) String() string {
+	return t.Pos.String() + " " + string(t.Type) + " " + strconv.Quote(string(t.Literal))
+}
+
+// End returns the position after the token.
+func (t Token) End() Position {
+	return t.Pos.Advance(t.Literal)
+}
+
+// MarshalJSON writes the token with its literal as a string.
+func (t Token) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    TokenType `json:"type"`
+		Literal string    `json:"literal"`
+		Pos     Position  `json:"pos"`
+	}{t.Type, string(t.Literal), t.Pos})
 }
 
 func GetHeadingToken(cnt int) TokenType {
@@ -97,40 +144,3 @@ func GetTabToken(cnt int) TokenType {
 		return STRING
 	}
 }
-
-func GetAsteriskToken(cnt int) TokenType {
-	switch cnt {
-	case 1:
-		return ASTERISK_ITALIC_BEGIN
-	case 2:
-		return ASTERISK_BOLD_BEGIN
-	case 3:
-		return ASTERISK_ITALIC_BOLD_BEGIN
-	default:
-		return STRING
-	}
-}
-
-func GetUnderScoreToken(cnt int) TokenType {
-	switch cnt {
-	case 1:
-		return UNDER_SCORE_ITALIC_BEGIN
-	case 2:
-		return UNDER_SCORE_BOLD_BEGIN
-	case 3:
-		return UNDER_SCORE_ITALIC_BOLD_BEGIN
-	default:
-		return STRING
-	}
-}
-
-func GetCitationToken(cnt int) TokenType {
-	switch cnt {
-	case 1:
-		return CITATION1
-	case 2:
-		return CITATION2
-	default:
-		return STRING
-	}
-}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/istsh/markdown-viewer/lexer"
+	"github.com/istsh/markdown-viewer/parser"
+	"github.com/istsh/markdown-viewer/renderer/ansi"
+)
+
+// defaultPager pages the output unless it fits on the screen, keeping the colors.
+const defaultPager = "less -R -F -X"
+
+// view renders a markdown file for the terminal and shows it with a pager.
+func view(args []string) error {
+	fs := flag.NewFlagSet("view", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: markdown-viewer view [-width N] [-no-pager] FILE")
+		fs.PrintDefaults()
+	}
+	width := fs.Int("width", 0, "wrap lines at `N` columns instead of the width of the terminal")
+	noPager := fs.Bool("no-pager", false, "write to stdout without a pager")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var in io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	terminal := isTerminal(os.Stdout)
+	if *width <= 0 {
+		*width = columns(terminal)
+	}
+
+	var out bytes.Buffer
+	if err := parser.New(lexer.NewReader(in)).RenderTo(&out, ansi.NewRenderer(*width)); err != nil {
+		return err
+	}
+
+	if !terminal || *noPager {
+		_, err := os.Stdout.Write(out.Bytes())
+		return err
+	}
+	return page(&out)
+}
+
+// page shows r with $PAGER, or writes it to stdout if the pager can't be started.
+func page(r io.Reader) error {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = defaultPager
+	}
+	args := strings.Fields(pager)
+	if len(args) == 0 {
+		_, err := io.Copy(os.Stdout, r)
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = r
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			_, err := io.Copy(os.Stdout, r)
+			return err
+		}
+		return err
+	}
+	return cmd.Wait()
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// columns returns the width of the terminal, or $COLUMNS if it isn't known.
+func columns(terminal bool) int {
+	if terminal {
+		if width := terminalWidth(os.Stdout); width > 0 {
+			return width
+		}
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 0
+}